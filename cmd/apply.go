@@ -0,0 +1,76 @@
+// Copyright © 2017 Sam Elliott <me@sam-e.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/elliottsam/vault-config/vault"
+	"github.com/spf13/cobra"
+)
+
+// applyCmd applies a plan saved by the plan command
+var applyCmd = &cobra.Command{
+	Use:   "apply [plan file]",
+	Short: "Applies a saved plan to Vault",
+	Long: `vault-config apply reads a plan saved with
+plan -out and applies only the changes it contains
+
+Before any changes are made the plan is recreated
+from the saved configuration, if the Vault server
+has changed since the plan was saved the apply
+is refused and a new plan must be created
+
+The key used to encrypt the plan is requested if
+not passed via the -key flag
+
+e.g.
+vault-config apply plan.vcplan -k mSskqBC85rA65lofPOaQcVtjjnHJ16rI+/rqZfkBwqs=
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			log.Fatal("Please supply the filename of a saved plan")
+		}
+		client := newClient()
+
+		sp, err := vault.ReadPlanFile(args[0], getKey())
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := client.CheckPlan(sp); err != nil {
+			log.Fatal(err)
+		}
+
+		plan := &vault.Plan{Changes: sp.Changes}
+		client.Parallelism = parallelism
+		client.EncryptionKey = getKey()
+		if err := client.ApplyPlan(sp.Config, plan); err != nil {
+			log.Fatal(err)
+		}
+		for _, v := range plan.Changes {
+			if v.Action != vault.ActionNoop {
+				fmt.Printf("Applied %s: %s.%s\n", v.Action, v.Resource, v.Name)
+			}
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(applyCmd)
+
+	applyCmd.Flags().StringVarP(&key, "key", "k", "", "Key the plan was encrypted with")
+	applyCmd.Flags().IntVar(&parallelism, "parallelism", vault.DefaultParallelism, "Number of resources to write at the same time")
+}
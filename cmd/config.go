@@ -15,6 +15,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"log"
 
@@ -25,7 +26,6 @@ import (
 	"github.com/elliottsam/vault-config/crypto"
 	"github.com/elliottsam/vault-config/template"
 	"github.com/elliottsam/vault-config/vault"
-	"github.com/hashicorp/hcl"
 	"github.com/hashicorp/vault/api"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -53,111 +53,143 @@ vault-config config -e -k mSskqBC85rA65lofPOaQcVtjjnHJ16rI+/rqZfkBwqs=
 
 This will cycle through all .vc and .vc.enc files
 decrypting those that require it
+
+If the -prune flag is set, objects in Vault that
+are not declared in the configuration will be
+deleted, this can be limited to specific resource
+types with the -prune-types flag, the root and
+default policies, system mounts and the token
+auth backend are never removed
 `,
 	Run: func(cmd *cobra.Command, args []string) {
-		var err error
-		cmdInit()
-		e := crypto.EncryptionObject{}
-		e.PlainText = e.ReadConfigFiles(filename)
-		if encrypted {
-			if key == "" {
-				e.Key, err = crypto.GetPassword()
-				if err != nil {
-					log.Fatal(err)
-				}
-			} else {
-				e.Key, err = base64.StdEncoding.DecodeString(key)
-				if err != nil {
-					log.Fatalf("Error base64 decoding key: %v", err)
-				}
+		vconf, client := loadConfig()
+
+		var pruneChanges []vault.Change
+		if prune {
+			var err error
+			pruneChanges, err = client.PlanPrune(vconf, pruneTypes)
+			if err != nil {
+				log.Fatalf("Error finding objects to prune: %v", err)
 			}
-			e.PlainText = crypto.JoinBytes(e.ReadEncryptedConfigFiles(filename), e.PlainText)
 		}
 
-		g := template.InitGenerator(varFile, e.PlainText)
-		e.PlainText = g.GenerateConfig()
-
-		c := api.DefaultConfig()
-		c.Address = vcVaultAddr
-		if vcVaultSkipVerify == true {
-			c.ConfigureTLS(&api.TLSConfig{Insecure: true})
+		client.Parallelism = parallelism
+		if vconf.GeneratesSecretIDs() {
+			client.EncryptionKey = getKey()
+		}
+		report, err := client.Apply(context.Background(), vconf)
+		for _, v := range report.Changes {
+			if v.Action == vault.ActionNoop {
+				fmt.Printf("Unchanged: %s.%s\n", v.Resource, v.Name)
+				continue
+			}
+			fmt.Printf("Applied %s: %s.%s\n", v.Action, v.Resource, v.Name)
 		}
-		client, err := vault.NewClient(c)
 		if err != nil {
-			log.Fatalf("Error creating Vault client: %v", err)
-
+			log.Fatal(err)
 		}
-		client.SetToken(vcVaultToken)
 
-		var vconf vault.Config
-		err = hcl.Unmarshal(e.PlainText, &vconf)
-		if err != nil {
-			log.Fatal(fmt.Errorf("Error reading HCL: %v", err))
+		if err := client.Prune(vconf, pruneChanges); err != nil {
+			log.Fatal(err)
+		}
+		for _, v := range pruneChanges {
+			fmt.Printf("Pruned %s: %s\n", v.Resource, v.Path)
 		}
+	},
+}
 
-		if vault.SecretsEncrypted(vconf) {
-			if e.Key != nil {
-				err = vconf.DecryptSecrets(e.Key)
-			} else if key != "" {
-				decodedKey, err = base64.StdEncoding.DecodeString(key)
-				if err != nil {
-					log.Fatalf("Error base64 decoding key: %v", err)
-				}
-				err = vconf.DecryptSecrets(decodedKey)
-			} else {
-				decodedKey, err = crypto.GetPassword()
-				if err != nil {
-					log.Fatalf("Error getting encryption key: %v", err)
-				}
-				err = vconf.DecryptSecrets(decodedKey)
+// loadConfig reads, decrypts and renders all configuration files and
+// returns the resulting config along with a client for the target Vault server
+func loadConfig() (vault.Config, *vault.VCClient) {
+	var err error
+	cmdInit()
+	e := crypto.EncryptionObject{}
+	e.PlainText = e.ReadConfigFiles(filename)
+	if encrypted {
+		if key == "" {
+			e.Key, err = crypto.GetPassword()
+			if err != nil {
+				log.Fatal(err)
 			}
+		} else {
+			e.Key, err = base64.StdEncoding.DecodeString(key)
 			if err != nil {
-				log.Fatalf("Error decrypting secrets: %v", err)
+				log.Fatalf("Error base64 decoding key: %v", err)
 			}
 		}
+		decodedKey = e.Key
+		e.PlainText = crypto.JoinBytes(e.ReadEncryptedConfigFiles(filename), e.PlainText)
+	}
 
-		for _, m := range vconf.Mounts {
-			if ok := client.MountExist(m.Path); !ok {
-				err := client.Mount(m.Path, vault.ConvertMapStringInterface(m.Config))
-				if err != nil {
-					log.Fatalf("Error creating mount: %v", err)
-				}
-			}
-			if err := client.TuneMount(m.Path, vault.ConvertMapStringInterface(m.Config.MountConfig)); err != nil {
-				log.Fatal(err)
-			}
-		}
+	g, err := template.InitGenerator(varFile, e.PlainText)
+	if err != nil {
+		log.Fatal(err)
+	}
+	e.PlainText, err = g.GenerateConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
 
-		for _, p := range vconf.Policies {
-			if err := client.PolicyAdd(p); err != nil {
-				log.Fatal(err)
-			}
-		}
+	client := newClient()
 
-		if vconf.Auth.Ldap != nil {
-			if err := vault.EnableAndConfigure(vconf.Auth.Ldap, client); err != nil {
-				log.Fatal(fmt.Errorf("Error creating Ldap auth:\n%s", err))
-			}
-		}
+	vconf, err := vault.ParseConfig(e.PlainText)
+	if err != nil {
+		log.Fatal(fmt.Errorf("Error reading HCL: %v", err))
+	}
+	for _, w := range vconf.Warnings() {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	}
 
-		if vconf.Auth.Github != nil {
-			if err := vault.EnableAndConfigure(vconf.Auth.Github, client); err != nil {
-				log.Fatal(fmt.Errorf("Error creating Github auth:\n%s", err))
-			}
+	if vault.SecretsEncrypted(vconf) {
+		if err := vconf.DecryptSecrets(getKey()); err != nil {
+			log.Fatalf("Error decrypting secrets: %v", err)
 		}
+	}
 
-		for _, v := range vconf.TokenRoles {
-			if err := client.WriteTokenRole(v); err != nil {
-				log.Fatalf("Error writing token role: %v", err)
-			}
-		}
+	return vconf, client
+}
 
-		for _, v := range vconf.Secrets {
-			if err := client.WriteSecret(v); err != nil {
-				log.Fatalf("Error: %v", err)
-			}
+// newClient returns a client for the Vault server being configured
+func newClient() *vault.VCClient {
+	cmdInit()
+	c := api.DefaultConfig()
+	c.Address = vcVaultAddr
+	if vcVaultSkipVerify == true {
+		c.ConfigureTLS(&api.TLSConfig{Insecure: true})
+	}
+	client, err := vault.NewClient(c)
+	if err != nil {
+		log.Fatalf("Error creating Vault client: %v", err)
+
+	}
+	client.SetToken(vcVaultToken)
+
+	return client
+}
+
+// getKey returns the encryption key, using the key already supplied
+// if there is one and requesting it from the terminal if not
+func getKey() []byte {
+	if decodedKey != nil {
+		return decodedKey
+	}
+	var err error
+	if key != "" {
+		decodedKey, err = base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			log.Fatalf("Error base64 decoding key: %v", err)
 		}
-	},
+	} else {
+		decodedKey, err = crypto.GetPassword()
+		if err != nil {
+			log.Fatalf("Error getting encryption key: %v", err)
+		}
+	}
+	if len(decodedKey) != 32 {
+		log.Fatalln("Key must be 32 bytes")
+	}
+
+	return decodedKey
 }
 
 func cmdInit() {
@@ -183,4 +215,7 @@ func init() {
 	configCmd.Flags().StringVarP(&varFile, "varFile", "v", "vault-config.vars", "Filename of vars to be used in templates")
 	configCmd.Flags().BoolVarP(&encrypted, "encrypted", "e", false, "Is this file encrypted")
 	configCmd.Flags().StringVarP(&key, "key", "k", "", "Encryption key this must be 32 bytes")
+	configCmd.Flags().BoolVar(&prune, "prune", false, "Delete objects from Vault that are not in the configuration")
+	configCmd.Flags().StringSliceVar(&pruneTypes, "prune-types", vault.PruneTypes(), "Resource types to prune")
+	configCmd.Flags().IntVar(&parallelism, "parallelism", vault.DefaultParallelism, "Number of resources to write at the same time")
 }
@@ -0,0 +1,97 @@
+// Copyright © 2017 Sam Elliott <me@sam-e.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var driftFormat string
+
+// driftCmd reports differences between the configuration and Vault
+var driftCmd = &cobra.Command{
+	Use:   "drift",
+	Short: "Reports drift between config and Vault",
+	Long: `vault-config drift compares the configuration with
+the Vault server and reports resources that are
+missing, differ from the configuration or exist in
+Vault without being declared
+
+The report can be written as text, json or junit
+with the -format flag, to stdout or to the file set
+with the -output flag
+
+The command exits with status 2 if drift is found
+
+e.g.
+vault-config drift -format junit -o drift.xml
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		switch driftFormat {
+		case "text", "json", "junit":
+		default:
+			log.Fatalf("Unknown report format: %s", driftFormat)
+		}
+
+		vconf, client := loadConfig()
+
+		report, err := client.Drift(vconf)
+		if err != nil {
+			log.Fatalf("Error checking for drift: %v", err)
+		}
+
+		var buf bytes.Buffer
+		switch driftFormat {
+		case "text":
+			err = report.WriteText(&buf)
+		case "json":
+			err = report.WriteJSON(&buf)
+		case "junit":
+			err = report.WriteJUnit(&buf)
+		}
+		if err != nil {
+			log.Fatalf("Error writing drift report: %v", err)
+		}
+
+		if output == "" {
+			fmt.Print(buf.String())
+		} else {
+			if err := ioutil.WriteFile(output, buf.Bytes(), 0600); err != nil {
+				log.Fatalf("Error writing drift report to file: %v", err)
+			}
+		}
+
+		if report.HasDrift() {
+			os.Exit(2)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(driftCmd)
+
+	driftCmd.Flags().StringVarP(&filename, "filename", "f", "", "Filename of configuration file")
+	driftCmd.Flags().StringVarP(&varFile, "varFile", "v", "vault-config.vars", "Filename of vars to be used in templates")
+	driftCmd.Flags().BoolVarP(&encrypted, "encrypted", "e", false, "Is this file encrypted")
+	driftCmd.Flags().StringVarP(&key, "key", "k", "", "Encryption key this must be 32 bytes")
+	driftCmd.Flags().StringVar(&driftFormat, "format", "text", "Report format: text, json or junit")
+	driftCmd.Flags().StringVarP(&output, "output", "o", "", "Filename to write report to")
+}
@@ -77,11 +77,17 @@ to quickly create a Cobra application.`,
 			log.Fatal(err)
 		}
 
-		tmpl := template.InitGenerator("", []byte(secret_tmpl))
+		tmpl, err := template.InitGenerator("", []byte(secret_tmpl))
+		if err != nil {
+			log.Fatal(err)
+		}
 		for _, v := range sPath {
 			tmpl.UpdateVarsMap(v, v)
 		}
-		e.PlainText = tmpl.GenerateConfig()
+		e.PlainText, err = tmpl.GenerateConfig()
+		if err != nil {
+			log.Fatal(err)
+		}
 
 		if encrypted {
 
@@ -0,0 +1,107 @@
+// Copyright © 2017 Sam Elliott <me@sam-e.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/elliottsam/vault-config/crypto"
+	"github.com/elliottsam/vault-config/template"
+	"github.com/spf13/cobra"
+)
+
+// importCmd generates configuration from an existing Vault server
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Generates configuration from an existing Vault server",
+	Long: `vault-config import reads mounts, policies, token
+roles and the LDAP and Github auth backends from the
+Vault server and writes them out as .vc files that
+can be used with the config command
+
+A file is written for each type of resource, these
+are written to the current directory unless the
+-output flag is set, existing files are not
+overwritten
+
+If the -encrypted flag is set, sensitive values in
+auth backend config are encrypted inline
+
+Secrets are not imported, use the export command
+
+e.g.
+vault-config import -o ./vault -e -g
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client := newClient()
+
+		vconf, err := client.Import()
+		if err != nil {
+			log.Fatalf("Error importing Vault configuration: %v", err)
+		}
+
+		if encrypted {
+			if generate {
+				decodedKey = crypto.RandomKey(32)
+				log.Printf("Generated Key: %s\n", base64.StdEncoding.EncodeToString(decodedKey))
+			}
+			if err := vconf.EncryptSensitive(getKey()); err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		files, err := template.RenderConfig(*vconf)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if output == "" {
+			output = "."
+		}
+		if err := os.MkdirAll(output, 0700); err != nil {
+			log.Fatalf("Error creating output directory: %v", err)
+		}
+		names := make([]string, 0, len(files))
+		for name := range files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			filename := filepath.Join(output, name)
+			if _, err := os.Stat(filename); err == nil {
+				log.Fatalf("Error: %s already exists, refusing to overwrite", filename)
+			}
+			if err := ioutil.WriteFile(filename, files[name], 0600); err != nil {
+				log.Fatalf("Error writing vault config to file: %v", err)
+			}
+			fmt.Printf("Written: %s\n", filename)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(importCmd)
+
+	importCmd.Flags().StringVarP(&output, "output", "o", "", "Directory to write configuration files to")
+	importCmd.Flags().BoolVarP(&encrypted, "encrypted", "e", false, "Encrypt sensitive values")
+	importCmd.Flags().StringVarP(&key, "key", "k", "", "Encryption key this must be 32 bytes")
+	importCmd.Flags().BoolVarP(&generate, "generate", "g", false, "Generate encyption key at runtime")
+}
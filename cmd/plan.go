@@ -0,0 +1,98 @@
+// Copyright © 2017 Sam Elliott <me@sam-e.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/elliottsam/vault-config/vault"
+	"github.com/spf13/cobra"
+)
+
+var planOut string
+
+// planCmd shows the changes that would be made to Vault
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Shows changes that config would make to Vault",
+	Long: `vault-config plan reads the configuration in the
+same way as the config command and compares every
+resource with the current state of the Vault server
+
+Each resource is shown as being created (+),
+updated (~) or left unchanged, along with the
+fields that differ, secret values are masked
+
+If the -prune flag is set, objects in Vault that
+are not declared in the configuration are shown as
+being deleted (-)
+
+No changes are made to the Vault server
+
+If the -out flag is set, the plan and the rendered
+configuration are saved to an encrypted file that
+can later be applied with the apply command
+
+e.g.
+vault-config plan -out plan.vcplan
+
+e.g.
+vault-config plan -e -k mSskqBC85rA65lofPOaQcVtjjnHJ16rI+/rqZfkBwqs=
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		vconf, client := loadConfig()
+
+		plan, err := client.Plan(vconf)
+		if err != nil {
+			log.Fatalf("Error creating plan: %v", err)
+		}
+		if prune {
+			pruneChanges, err := client.PlanPrune(vconf, pruneTypes)
+			if err != nil {
+				log.Fatalf("Error finding objects to prune: %v", err)
+			}
+			plan.Changes = append(plan.Changes, pruneChanges...)
+		}
+		plan.Print(os.Stdout)
+
+		if planOut != "" {
+			sp := vault.SavedPlan{
+				Config:  vconf,
+				Changes: plan.Changes,
+			}
+			if prune {
+				sp.PruneTypes = pruneTypes
+			}
+			if err := vault.WritePlanFile(planOut, getKey(), sp); err != nil {
+				log.Fatal(err)
+			}
+			fmt.Printf("Plan saved to: %s\n", planOut)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(planCmd)
+
+	planCmd.Flags().StringVarP(&filename, "filename", "f", "", "Filename of configuration file")
+	planCmd.Flags().StringVarP(&varFile, "varFile", "v", "vault-config.vars", "Filename of vars to be used in templates")
+	planCmd.Flags().BoolVarP(&encrypted, "encrypted", "e", false, "Is this file encrypted")
+	planCmd.Flags().StringVarP(&key, "key", "k", "", "Encryption key this must be 32 bytes")
+	planCmd.Flags().BoolVar(&prune, "prune", false, "Show objects in Vault that are not in the configuration as deleted")
+	planCmd.Flags().StringVar(&planOut, "out", "", "Filename to save encrypted plan to")
+	planCmd.Flags().StringSliceVar(&pruneTypes, "prune-types", vault.PruneTypes(), "Resource types to prune")
+}
@@ -34,6 +34,9 @@ var (
 	vcVaultAddr       string
 	vcVaultToken      string
 	vcVaultSkipVerify bool
+	prune             bool
+	pruneTypes        []string
+	parallelism       int
 )
 
 // RootCmd represents the base command when called without any subcommands
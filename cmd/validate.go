@@ -0,0 +1,120 @@
+// Copyright © 2017 Sam Elliott <me@sam-e.co.uk>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/elliottsam/vault-config/crypto"
+	"github.com/elliottsam/vault-config/template"
+	"github.com/elliottsam/vault-config/vault"
+	"github.com/spf13/cobra"
+)
+
+// validateCmd checks configuration files without contacting Vault
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validates configuration files",
+	Long: `vault-config validate checks configuration files
+without contacting Vault, each file is checked on its
+own so errors are reported as file:line:col
+
+Unknown keys, missing required fields, resources
+declared more than once, invalid TTLs and invalid
+policy rules are reported
+
+e.g.
+vault-config validate
+vault-config validate -f config.vc
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		names, err := configFiles()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		var files []vault.ConfigFile
+		for _, n := range names {
+			data, err := ioutil.ReadFile(n)
+			if err != nil {
+				log.Fatalf("Error reading file: %v", err)
+			}
+			if strings.HasSuffix(n, ".vc.enc") {
+				e := crypto.EncryptionObject{Key: getKey(), WrappedData: string(data)}
+				if err := e.UnwrapCrypto(); err != nil {
+					log.Fatalf("Error unwrapping encrypted file: %v\nErr: %v", n, err)
+				}
+				if err := e.Decrypt(); err != nil {
+					log.Fatalf("Error decrypting file: %v\nErr: %v", n, err)
+				}
+				data = e.PlainText
+			}
+			g, err := template.InitOfflineGenerator(varFile, data)
+			if err != nil {
+				log.Fatal(err)
+			}
+			data, err = g.GenerateConfig()
+			if err != nil {
+				log.Fatalf("Error in %s: %v", n, err)
+			}
+			files = append(files, vault.ConfigFile{Name: n, Data: data})
+		}
+
+		errs := vault.Validate(files)
+		for _, e := range errs {
+			fmt.Println(e)
+		}
+		if len(errs) > 0 {
+			os.Exit(1)
+		}
+		fmt.Printf("Configuration is valid, %d file(s) checked\n", len(files))
+	},
+}
+
+// configFiles returns the file set with the filename flag, or the .vc files
+// in the working directory, including .vc.enc files when encrypted is set
+func configFiles() ([]string, error) {
+	if filename != "" {
+		return []string{filename}, nil
+	}
+	files, err := filepath.Glob("*.vc")
+	if err != nil {
+		return nil, fmt.Errorf("Error listing config files: %v", err)
+	}
+	if encrypted {
+		enc, err := filepath.Glob("*.vc.enc")
+		if err != nil {
+			return nil, fmt.Errorf("Error listing config files: %v", err)
+		}
+		files = append(files, enc...)
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+func init() {
+	RootCmd.AddCommand(validateCmd)
+	validateCmd.Flags().StringVarP(&filename, "filename", "f", "", "Filename of configuration file")
+	validateCmd.Flags().StringVarP(&varFile, "varFile", "v", "vault-config.vars", "Filename of vars to be used in templates")
+	validateCmd.Flags().BoolVarP(&encrypted, "encrypted", "e", false, "Validate encrypted files as well")
+	validateCmd.Flags().StringVarP(&key, "key", "k", "", "Encryption key this must be 32 bytes")
+}
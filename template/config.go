@@ -0,0 +1,261 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/elliottsam/vault-config/vault"
+	"github.com/hashicorp/hcl/hcl/printer"
+)
+
+const hclMountTemplate = `{{ range . }}
+mount "{{ .Name }}" {
+  path = {{ hclValue .Path }}
+  config {
+    type = {{ hclValue .Config.PathType }}
+    description = {{ hclValue .Config.Description }}
+    {{- if .Config.Options }}
+    options {{ hclValue .Config.Options }}
+    {{- end }}
+    {{- if or .Config.MountConfig.DefaultLeaseTTL .Config.MountConfig.MaxLeaseTTL }}
+    mountconfig {
+      {{- if .Config.MountConfig.DefaultLeaseTTL }}
+      default_lease_ttl = {{ hclValue .Config.MountConfig.DefaultLeaseTTL }}
+      {{- end }}
+      {{- if .Config.MountConfig.MaxLeaseTTL }}
+      max_lease_ttl = {{ hclValue .Config.MountConfig.MaxLeaseTTL }}
+      {{- end }}
+    }
+    {{- end }}
+  }
+  {{- with .Metadata }}
+  metadata {
+{{ hclBody . }}  }
+  {{- end }}
+}
+{{ end }}`
+
+const hclPolicyTemplate = `{{ range . }}
+policy "{{ .Name }}" {
+  rules = <<EOF
+{{ trimNewline .Rules }}
+EOF
+}
+{{ end }}`
+
+const hclTokenRoleTemplate = `{{ range . }}
+token_role "{{ .Name }}" {
+  options {{ hclValue .Options }}
+}
+{{ end }}`
+
+const hclAuthTemplate = `auth {
+{{- range .Ldap }}
+  ldap{{ if .Name }} "{{ .Name }}"{{ end }} {
+    {{- if .Path }}
+    path = {{ hclValue .Path }}
+    {{- end }}
+    description = {{ hclValue .Description }}
+    authconfig {{ hclValue .AuthConfig }}
+    {{- range .Users }}
+    user "{{ .Name }}" {
+      options {{ hclValue .Options }}
+    }
+    {{- end }}
+    {{- range .Groups }}
+    group "{{ .Name }}" {
+      options {{ hclValue .Options }}
+    }
+    {{- end }}
+    {{- with hclBody .MountConfig }}
+    mountconfig {
+{{ . }}    }
+    {{- end }}
+  }
+{{- end }}
+{{- range .Github }}
+  github{{ if .Name }} "{{ .Name }}"{{ end }} {
+    {{- if .Path }}
+    path = {{ hclValue .Path }}
+    {{- end }}
+    description = {{ hclValue .Description }}
+    authconfig {{ hclValue .AuthConfig }}
+    {{- range .Users }}
+    users "{{ .Name }}" {
+      options {{ hclValue .Options }}
+    }
+    {{- end }}
+    {{- range .Groups }}
+    teams "{{ .Name }}" {
+      options {{ hclValue .Options }}
+    }
+    {{- end }}
+    {{- with hclBody .MountConfig }}
+    mountconfig {
+{{ . }}    }
+    {{- end }}
+  }
+{{- end }}
+}
+`
+
+// hclValue formats a value as HCL, maps are formatted as an object
+// so they can follow a block name
+func hclValue(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return `""`
+	case string:
+		return strconv.Quote(t)
+	case bool, int, int64, float64, json.Number:
+		return fmt.Sprint(t)
+	case []interface{}:
+		var items []string
+		for _, i := range t {
+			items = append(items, hclValue(i))
+		}
+		return fmt.Sprintf("[%s]", strings.Join(items, ", "))
+	case []string:
+		var items []string
+		for _, i := range t {
+			items = append(items, strconv.Quote(i))
+		}
+		return fmt.Sprintf("[%s]", strings.Join(items, ", "))
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var buf bytes.Buffer
+		buf.WriteString("{\n")
+		for _, k := range keys {
+			fmt.Fprintf(&buf, "%s = %s\n", k, hclValue(t[k]))
+		}
+		buf.WriteString("}")
+		return buf.String()
+	}
+
+	return strconv.Quote(fmt.Sprint(v))
+}
+
+// hclBody formats the fields of a struct with hcl tags as the body of a
+// block, empty fields are left out
+func hclBody(v reflect.Value) string {
+	var buf bytes.Buffer
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag := strings.Split(t.Field(i).Tag.Get("hcl"), ",")
+		name := tag[0]
+		if name == "" || name == "-" || t.Field(i).PkgPath != "" {
+			continue
+		}
+		// fields that are pointers are written when they are set, even to
+		// their zero value, e.g. max_versions = 0
+		f := v.Field(i)
+		if f.Kind() == reflect.Ptr {
+			if f.IsNil() {
+				continue
+			}
+			f = f.Elem()
+		} else if reflect.DeepEqual(f.Interface(), reflect.Zero(f.Type()).Interface()) {
+			continue
+		}
+		switch {
+		case f.Kind() == reflect.Struct:
+			fmt.Fprintf(&buf, "%s {\n%s}\n", name, hclBody(f))
+		case f.Kind() == reflect.Slice && f.Type().Elem().Kind() == reflect.Struct:
+			for j := 0; j < f.Len(); j++ {
+				fmt.Fprintf(&buf, "%s%s {\n%s}\n", name, hclLabel(f.Index(j)), hclBody(f.Index(j)))
+			}
+		case f.Kind() == reflect.Map:
+			fmt.Fprintf(&buf, "%s %s\n", name, hclValue(f.Interface()))
+		default:
+			fmt.Fprintf(&buf, "%s = %s\n", name, hclValue(f.Interface()))
+		}
+	}
+
+	return buf.String()
+}
+
+// hclLabel returns the label of a block from the field of a struct
+// tagged with ",key"
+func hclLabel(v reflect.Value) string {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("hcl") == ",key" {
+			return fmt.Sprintf(" %q", v.Field(i).Interface())
+		}
+	}
+
+	return ""
+}
+
+// renderResource formats a block of a registered type as an HCL block
+func renderResource(r vault.Block) string {
+	v := reflect.Indirect(reflect.ValueOf(r))
+
+	return fmt.Sprintf("%s%s {\n%s}\n", r.Kind(), hclLabel(v), hclBody(v))
+}
+
+// RenderConfig converts a Config into formatted HCL, returning the contents
+// of a .vc file for each type of resource in the configuration
+func RenderConfig(conf vault.Config) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	funcs := template.FuncMap{
+		"hclValue":    hclValue,
+		"hclBody":     func(v interface{}) string { return hclBody(reflect.Indirect(reflect.ValueOf(v))) },
+		"trimNewline": func(s string) string { return strings.TrimRight(s, "\n") },
+	}
+	sections := []struct {
+		filename string
+		tmpl     string
+		data     interface{}
+		empty    bool
+	}{
+		{"mounts.vc", hclMountTemplate, conf.Mounts, len(conf.Mounts) == 0},
+		{"policies.vc", hclPolicyTemplate, conf.Policies, len(conf.Policies) == 0},
+		{"token_roles.vc", hclTokenRoleTemplate, conf.TokenRoles, len(conf.TokenRoles) == 0},
+		{"auth.vc", hclAuthTemplate, conf.Auth, len(conf.Auth.Ldap) == 0 && len(conf.Auth.Github) == 0},
+	}
+
+	for _, s := range sections {
+		if s.empty {
+			continue
+		}
+		var buf bytes.Buffer
+		tmpl := template.Must(template.New(s.filename).Funcs(funcs).Parse(s.tmpl))
+		if err := tmpl.Execute(&buf, s.data); err != nil {
+			return nil, fmt.Errorf("Error rendering %s: %v", s.filename, err)
+		}
+		out, err := printer.Format(buf.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("Error formatting %s: %v", s.filename, err)
+		}
+		files[s.filename] = out
+	}
+
+	resources := make(map[string]*bytes.Buffer)
+	for _, r := range conf.Resources {
+		filename := fmt.Sprintf("%s.vc", r.Kind())
+		if resources[filename] == nil {
+			resources[filename] = &bytes.Buffer{}
+		}
+		fmt.Fprintf(resources[filename], "\n%s", renderResource(r))
+	}
+	for filename, buf := range resources {
+		out, err := printer.Format(buf.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("Error formatting %s: %v", filename, err)
+		}
+		files[filename] = out
+	}
+
+	return files, nil
+}
@@ -49,18 +49,24 @@ func (g *Generator) templateLookup(key string) (interface{}, error) {
 }
 
 func (g *Generator) templateLookupSecret(path string, targetPath ...string) (interface{}, error) {
-	s, err := g.client.Logical().Read(path)
-	if err != nil || s == nil {
+	if g.client == nil {
+		return "", nil
+	}
+	data, exists, err := g.client.ReadSecret(strings.Trim(path, "/"))
+	if err != nil || !exists {
 		return nil, fmt.Errorf("reading from vault path: %s\nError: %v", path, err)
 	}
-	for k, v := range s.Data {
-		base64.StdEncoding.EncodeToString([]byte(v.(string)))
-		s.Data[k] = fmt.Sprintf("@base64(%s)", base64.StdEncoding.EncodeToString([]byte(v.(string))))
+	for k, v := range data {
+		str, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("reading from vault path: %s\nError: value of %s is a %T, only string values can be copied", path, k, v)
+		}
+		data[k] = fmt.Sprintf("@base64(%s)", base64.StdEncoding.EncodeToString([]byte(str)))
 	}
 
 	tmplSecret := secret{
 		Path: path,
-		Data: s.Data,
+		Data: data,
 	}
 	if len(targetPath) > 0 {
 		tmplSecret.Path = targetPath[0]
@@ -69,58 +75,87 @@ func (g *Generator) templateLookupSecret(path string, targetPath ...string) (int
 	tmplSecret.Name = sp[len(sp)-1]
 
 	var buf bytes.Buffer
-	tmpl := template.Must(template.New("secret").Parse(hclSecretTemplate))
+	tmpl, err := template.New("secret").Parse(hclSecretTemplate)
+	if err != nil {
+		return nil, err
+	}
 	if err := tmpl.Execute(&buf, tmplSecret); err != nil {
-		panic(err)
+		return nil, fmt.Errorf("rendering secret: %s\nError: %v", path, err)
 	}
 
 	return buf.String(), nil
 }
 
-func InitGenerator(varsFile string, config []byte) *Generator {
+// InitGenerator returns a Generator for the config, loading template
+// variables from varsFile if it exists
+func InitGenerator(varsFile string, config []byte) (*Generator, error) {
 	var err error
 	g := Generator{
 		config: config,
 	}
 	g.client, err = vault.NewClient(nil)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("Error creating Vault client: %v", err)
+	}
+	g.tmpl = template.New("").Funcs(template.FuncMap{
+		"Lookup":       g.templateLookup,
+		"LookupSecret": g.templateLookupSecret,
+	})
+
+	if err := g.readVars(varsFile); err != nil {
+		return nil, err
+	}
+
+	return &g, nil
+}
+
+// InitOfflineGenerator returns a Generator that does not contact Vault,
+// LookupSecret renders nothing, it is used to validate configuration
+func InitOfflineGenerator(varsFile string, config []byte) (*Generator, error) {
+	g := Generator{
+		config: config,
 	}
 	g.tmpl = template.New("").Funcs(template.FuncMap{
 		"Lookup":       g.templateLookup,
 		"LookupSecret": g.templateLookupSecret,
 	})
 
-	g.readVars(varsFile)
+	if err := g.readVars(varsFile); err != nil {
+		return nil, err
+	}
 
-	return &g
+	return &g, nil
 }
 
-func (g *Generator) GenerateConfig() []byte {
+// GenerateConfig executes the templates in the config and returns the result
+func (g *Generator) GenerateConfig() ([]byte, error) {
 	var buf bytes.Buffer
-	template.Must(g.tmpl.Parse(string(g.config)))
+	if _, err := g.tmpl.Parse(string(g.config)); err != nil {
+		return nil, fmt.Errorf("Error parsing template: %v", err)
+	}
 	if err := g.tmpl.Execute(&buf, g.vars); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		return nil, fmt.Errorf("Error executing template: %v", err)
 	}
 
-	return buf.Bytes()
+	return buf.Bytes(), nil
 }
 
-func (g *Generator) readVars(filename string) {
+func (g *Generator) readVars(filename string) error {
 	_, err := os.Stat(filename)
 	if err != nil {
 		g.vars = make(map[string]interface{})
-		return
+		return nil
 	}
 	vars, err := ioutil.ReadFile(filename)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("Error reading vars file: %v", err)
 	}
 
 	if err := hcl.Unmarshal(vars, &g.vars); err != nil {
-		panic(err)
+		return fmt.Errorf("Error reading HCL from vars file: %s\nError: %v", filename, err)
 	}
+
+	return nil
 }
 
 func (g *Generator) UpdateVarsMap(key, value string) {
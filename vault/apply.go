@@ -0,0 +1,251 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// Report lists the resources written to Vault by Apply
+type Report struct {
+	Changes []Change
+}
+
+func (r *Report) add(resource, name, path string, action ChangeAction) {
+	r.Changes = append(r.Changes, Change{
+		Resource: resource,
+		Name:     name,
+		Path:     path,
+		Action:   action,
+	})
+}
+
+// DefaultParallelism is the number of resources Apply writes at the same time
+const DefaultParallelism = 10
+
+// ApplyError lists every resource that failed to apply
+type ApplyError struct {
+	Errors []error
+}
+
+func (e *ApplyError) Error() string {
+	msg := fmt.Sprintf("%d error(s) applying configuration:", len(e.Errors))
+	for _, err := range e.Errors {
+		msg += fmt.Sprintf("\n  * %v", err)
+	}
+
+	return msg
+}
+
+// Apply writes the configuration to the Vault server, every resource in the
+// configuration is written in dependency order with up to DefaultParallelism
+// resources written at once, mounts and auth backends are created if they do
+// not already exist. Resources that depend on a resource that failed are
+// skipped, every failure is returned in an *ApplyError and the report lists
+// the resources that were written
+func Apply(ctx context.Context, client *api.Client, conf Config) (Report, error) {
+	c := &VCClient{Client: client, Parallelism: DefaultParallelism}
+
+	return c.Apply(ctx, conf)
+}
+
+// Apply writes the configuration to the Vault server using the client's
+// Parallelism, see Apply
+func (c *VCClient) Apply(ctx context.Context, conf Config) (Report, error) {
+	resources, err := conf.AllResources()
+	if err != nil {
+		return Report{}, err
+	}
+	s, err := c.withSnapshot()
+	if err != nil {
+		return Report{}, err
+	}
+	if err := s.checkMounts(resources); err != nil {
+		return Report{}, err
+	}
+
+	run := make([]bool, len(resources))
+	for i := range run {
+		run[i] = true
+	}
+
+	return s.applyResources(ctx, resources, run, func(r Resource) (ChangeAction, error) {
+		state, exists, err := r.Read(s)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return ActionCreate, r.Create(s)
+		}
+		if _, ok := r.(changeOnly); ok && r.Diff(state, exists).Action == ActionNoop {
+			return ActionNoop, nil
+		}
+
+		return ActionUpdate, r.Update(s)
+	})
+}
+
+// changeOnly is implemented by resources that Apply only writes when they
+// differ from Vault, such as secrets which get a new version every time
+// they are written to a KV version 2 mount
+type changeOnly interface {
+	changeOnly()
+}
+
+// ApplyPlan makes the create, update and delete changes in a plan using the
+// configuration the plan was created from, resources without changes are
+// left untouched. Deletes are made once every create and update has succeeded
+func (c *VCClient) ApplyPlan(conf Config, p *Plan) error {
+	resources, err := conf.AllResources()
+	if err != nil {
+		return err
+	}
+	index := make(map[string]int)
+	for i, r := range resources {
+		index[resourceKey(r.Kind(), r.VaultPath())] = i
+	}
+
+	run := make([]bool, len(resources))
+	actions := make(map[string]ChangeAction)
+	var deletes []Change
+	for _, ch := range p.Changes {
+		switch ch.Action {
+		case ActionDelete:
+			deletes = append(deletes, ch)
+		case ActionCreate, ActionUpdate:
+			key := resourceKey(ch.Resource, ch.Path)
+			i, ok := index[key]
+			if !ok {
+				return fmt.Errorf("Error applying %s %s.%s: resource not found in configuration", ch.Action, ch.Resource, ch.Name)
+			}
+			run[i] = true
+			actions[key] = ch.Action
+		}
+	}
+
+	_, err = c.applyResources(context.Background(), resources, run, func(r Resource) (ChangeAction, error) {
+		action := actions[resourceKey(r.Kind(), r.VaultPath())]
+		if action == ActionCreate {
+			return action, r.Create(c)
+		}
+
+		return action, r.Update(c)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, ch := range deletes {
+		if err := c.Prune(conf, []Change{ch}); err != nil {
+			return fmt.Errorf("Error applying %s %s.%s: %v", ch.Action, ch.Resource, ch.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// applyResources calls apply for each resource marked to run, up to
+// Parallelism at a time. A resource is started once every resource it
+// depends on has finished, if one of them failed it is skipped
+func (c *VCClient) applyResources(ctx context.Context, resources []Resource, run []bool, apply func(Resource) (ChangeAction, error)) (Report, error) {
+	deps, err := dependencies(resources)
+	if err != nil {
+		return Report{}, err
+	}
+	parallelism := c.Parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	// waiting counts the unfinished dependencies of each resource
+	waiting := make([]int, len(resources))
+	dependents := make([][]int, len(resources))
+	for i, d := range deps {
+		for _, j := range d {
+			if i != j && run[i] && run[j] {
+				waiting[i]++
+				dependents[j] = append(dependents[j], i)
+			}
+		}
+	}
+	var ready []int
+	for i := range resources {
+		if run[i] && waiting[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	type result struct {
+		i      int
+		action ChangeAction
+		err    error
+	}
+	var (
+		results = make(chan result)
+		running int
+		actions = make([]ChangeAction, len(resources))
+		failed  = make([]bool, len(resources))
+		errs    []error
+	)
+	finish := func(i int) {
+		for _, d := range dependents[i] {
+			if failed[i] {
+				failed[d] = true
+			}
+			waiting[d]--
+			if waiting[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+	for len(ready) > 0 || running > 0 {
+		for len(ready) > 0 && running < parallelism {
+			i := ready[0]
+			ready = ready[1:]
+			r := resources[i]
+			if ctx.Err() != nil {
+				failed[i] = true
+				finish(i)
+				continue
+			}
+			if failed[i] {
+				errs = append(errs, fmt.Errorf("Skipped %s: %s, a resource it depends on failed", r.Kind(), r.ID()))
+				finish(i)
+				continue
+			}
+			running++
+			go func(i int) {
+				action, err := apply(resources[i])
+				results <- result{i, action, err}
+			}(i)
+		}
+		if running == 0 {
+			continue
+		}
+		res := <-results
+		running--
+		if res.err != nil {
+			r := resources[res.i]
+			errs = append(errs, fmt.Errorf("Error writing %s: %s\nError: %v", r.Kind(), r.ID(), res.err))
+			failed[res.i] = true
+		}
+		actions[res.i] = res.action
+		finish(res.i)
+	}
+
+	var report Report
+	for i, r := range resources {
+		if run[i] && !failed[i] {
+			report.add(r.Kind(), r.ID(), r.VaultPath(), actions[i])
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return report, &ApplyError{Errors: errs}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,92 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyResources(t *testing.T) {
+	conf := Config{
+		Policies: []Policy{
+			{Name: "a"},
+			{Name: "b", DependsOn: []string{"policy.a"}},
+			{Name: "c"},
+			{Name: "d", DependsOn: []string{"policy.c"}},
+			{Name: "e", DependsOn: []string{"policy.d"}},
+		},
+	}
+	for i := 0; i < 20; i++ {
+		conf.Policies = append(conf.Policies, Policy{Name: fmt.Sprintf("p%d", i)})
+	}
+	resources, err := conf.AllResources()
+	if err != nil {
+		t.Fatalf("Error sorting resources: %v", err)
+	}
+	run := make([]bool, len(resources))
+	for i := range run {
+		run[i] = true
+	}
+
+	var (
+		mu        sync.Mutex
+		done      = make(map[string]bool)
+		active    int
+		maxActive int
+	)
+	c := &VCClient{Parallelism: 4}
+	report, err := c.applyResources(context.Background(), resources, run, func(r Resource) (ChangeAction, error) {
+		mu.Lock()
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		if d, ok := r.(Dependent); ok {
+			for _, ref := range d.DependsOn() {
+				assert.True(t, done[ref], "%s should be applied after %s", resourceRef(r), ref)
+			}
+		}
+		mu.Unlock()
+
+		defer func() {
+			mu.Lock()
+			active--
+			done[resourceRef(r)] = true
+			mu.Unlock()
+		}()
+		if r.ID() == "c" {
+			return ActionCreate, fmt.Errorf("permission denied")
+		}
+
+		return ActionCreate, nil
+	})
+
+	assert.True(t, maxActive <= 4, "No more than Parallelism resources should be applied at once")
+	assert.False(t, done["policy.d"] || done["policy.e"], "Resources depending on a failed resource should be skipped")
+	assert.Equal(t, len(resources)-3, len(report.Changes), "Report should contain every resource applied")
+	if assert.IsType(t, &ApplyError{}, err, "Failures should be returned in an ApplyError") {
+		assert.Equal(t, 3, len(err.(*ApplyError).Errors), "Every failed and skipped resource should be reported")
+	}
+}
+
+// applyAndPlan applies the configuration and checks that a plan made
+// afterwards has no changes for resources of the given kinds, or for every
+// resource when no kinds are given, so the resources converge
+func applyAndPlan(t *testing.T, c *VCClient, conf Config, kinds ...string) Report {
+	report, err := c.Apply(context.Background(), conf)
+	assert.NoError(t, err, "Apply should not return an error: %v", err)
+	p, err := c.Plan(conf)
+	if !assert.NoError(t, err, "Plan should not return an error: %v", err) {
+		return report
+	}
+	for _, ch := range p.Changes {
+		if len(kinds) == 0 || contains(kinds, ch.Resource) {
+			assert.Equal(t, ActionNoop, ch.Action, "Resources should be unchanged after apply: %s.%s %v", ch.Resource, ch.Name, ch.Fields)
+		}
+	}
+
+	return report
+}
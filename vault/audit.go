@@ -0,0 +1,156 @@
+package vault
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+)
+
+func init() {
+	Register(ResourceType{Name: "audit", New: func() Block { return &Audit{} }, List: listAudit})
+}
+
+// Audit is an audit device, such as a file, syslog or socket, the device is
+// enabled at Path which defaults to the name of the block
+type Audit struct {
+	Name        string                 `hcl:",key"`
+	Type        string                 `hcl:"type" validate:"required" mapstructure:"type"`
+	Path        string                 `hcl:"path"`
+	Description string                 `hcl:"description" mapstructure:"description"`
+	Options     map[string]interface{} `hcl:"options" mapstructure:"options"`
+	Local       bool                   `hcl:"local" mapstructure:"local"`
+	Depends     []string               `hcl:"depends_on"`
+}
+
+func (a *Audit) Kind() string { return "audit" }
+func (a *Audit) ID() string   { return a.Name }
+
+func (a *Audit) VaultPath() string {
+	return fmt.Sprintf("sys/audit/%s", a.path())
+}
+
+func (a *Audit) DependsOn() []string { return a.Depends }
+
+// changeOnly as updating a device disables and enables it again, which
+// would leave a gap in the audit log every time the configuration is applied
+func (a *Audit) changeOnly() {}
+
+func (a *Audit) path() string {
+	if p := strings.Trim(a.Path, "/"); p != "" {
+		return p
+	}
+
+	return a.Name
+}
+
+// options returns the options of the device as strings, which is how Vault
+// stores and returns them
+func (a *Audit) options() map[string]string {
+	if len(a.Options) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(a.Options))
+	for k, v := range a.Options {
+		out[k] = fmt.Sprint(v)
+	}
+
+	return out
+}
+
+func (a *Audit) Read(c *VCClient) (map[string]interface{}, bool, error) {
+	audit, err := c.Sys().ListAudit()
+	if err != nil {
+		return nil, false, fmt.Errorf("Error listing audit devices: %v", err)
+	}
+	ao, ok := audit[a.path()+"/"]
+	if !ok {
+		return nil, false, nil
+	}
+	options := make(map[string]interface{}, len(ao.Options))
+	for k, v := range ao.Options {
+		options[k] = v
+	}
+
+	return map[string]interface{}{
+		"type":        ao.Type,
+		"description": ao.Description,
+		"options":     options,
+		"local":       ao.Local,
+	}, true, nil
+}
+
+// Diff compares the device with Vault, local is compared even when false so
+// a device that is no longer local is enabled again
+func (a *Audit) Diff(state map[string]interface{}, exists bool) Change {
+	desired := ConvertMapStringInterface(a)
+	desired["local"] = a.Local
+
+	return diffResource(a.Kind(), a.ID(), a.VaultPath(), desired, state, exists)
+}
+
+func (a *Audit) Create(c *VCClient) error {
+	err := c.Sys().EnableAuditWithOptions(a.path(), &api.EnableAuditOptions{
+		Type:        a.Type,
+		Description: a.Description,
+		Options:     a.options(),
+		Local:       a.Local,
+	})
+	if err != nil {
+		return fmt.Errorf("Error enabling audit device: %s\nError: %v", a.path(), err)
+	}
+
+	return nil
+}
+
+// Update disables and enables the device again, Vault has no way to change
+// an audit device once it is enabled
+func (a *Audit) Update(c *VCClient) error {
+	if err := a.Delete(c); err != nil {
+		return err
+	}
+
+	return a.Create(c)
+}
+
+func (a *Audit) Delete(c *VCClient) error {
+	if err := c.Sys().DisableAudit(a.path()); err != nil {
+		return fmt.Errorf("Error disabling audit device: %s\nError: %v", a.path(), err)
+	}
+
+	return nil
+}
+
+// listAudit returns every audit device on the server, devices are named by path
+func listAudit(c *VCClient, conf Config) ([]Resource, error) {
+	audit, err := c.Sys().ListAudit()
+	if err != nil {
+		return nil, fmt.Errorf("Error listing audit devices: %v", err)
+	}
+	keys := make([]string, 0, len(audit))
+	for k := range audit {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var out []Resource
+	for _, k := range keys {
+		ao := audit[k]
+		a := &Audit{
+			Name:        strings.TrimSuffix(k, "/"),
+			Type:        ao.Type,
+			Description: ao.Description,
+			Local:       ao.Local,
+		}
+		if len(ao.Options) > 0 {
+			a.Options = make(map[string]interface{}, len(ao.Options))
+			for o, v := range ao.Options {
+				a.Options[o] = v
+			}
+		}
+		out = append(out, a)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,147 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const auditConfig = `
+audit "file" {
+  type = "file"
+  description = "Audit log"
+  options {
+    file_path = "/var/log/vault/audit.log"
+    log_raw = false
+  }
+}
+
+audit "remote" {
+  type = "syslog"
+  path = "syslog/remote"
+  local = true
+}
+`
+
+func TestAudit_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   []string
+	}{
+		{name: "valid", config: auditConfig},
+		{
+			name: "missing type",
+			config: `audit "file" {
+  path = "file"
+}`,
+			want: []string{"audit.vc:1:1: audit is missing required field: type"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, validationMessages(Validate([]ConfigFile{{Name: "audit.vc", Data: []byte(tt.config)}})),
+				"Audit devices should require a type")
+		})
+	}
+}
+
+func TestAudit_Paths(t *testing.T) {
+	conf, err := ParseConfig([]byte(auditConfig))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	resources, err := conf.AllResources()
+	assert.NoError(t, err, "Sorting resources should return no error: %v", err)
+	assert.Equal(t, []string{"audit.file", "audit.remote"}, refs(resources), "Both devices should be configured")
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"enabled at its name", "sys/audit/file"},
+		{"enabled at its path", "sys/audit/syslog/remote"},
+	}
+	for i, tt := range tests {
+		assert.Equal(t, tt.path, resources[i].VaultPath(), "Devices should be %s", tt.name)
+	}
+}
+
+func TestAudit_Apply(t *testing.T) {
+	f, c := newFakeVault(t)
+	defer f.Close()
+	conf, err := ParseConfig([]byte(auditConfig))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	applyAndPlan(t, c, conf)
+	assert.Equal(t, map[string]interface{}{
+		"type":        "file",
+		"description": "Audit log",
+		"options":     map[string]interface{}{"file_path": "/var/log/vault/audit.log", "log_raw": "false"},
+		"local":       false,
+	}, f.get("sys/audit/file"), "Devices should be enabled with their options as strings")
+	assert.Equal(t, true, f.get("sys/audit/syslog/remote")["local"], "Devices should be enabled at their path")
+
+	// unchanged devices are not enabled again as that would give them a
+	// new accessor, changed devices are disabled and enabled again
+	f.resetWrites()
+	applyAndPlan(t, c, conf)
+	assert.Empty(t, f.writes, "Unchanged devices should not be enabled again")
+	_, err = c.Logical().Write("sys/audit/syslog/remote", map[string]interface{}{"type": "syslog", "local": false})
+	assert.NoError(t, err, "Enabling audit device should not return an error: %v", err)
+	f.resetWrites()
+	applyAndPlan(t, c, conf)
+	assert.Equal(t, []string{"DELETE sys/audit/syslog/remote", "PUT sys/audit/syslog/remote"}, f.writes,
+		"Changed devices should be disabled and enabled again")
+	assert.Equal(t, true, f.get("sys/audit/syslog/remote")["local"], "Changed devices should be enabled with their options")
+
+	changes, err := c.PlanPrune(Config{}, []string{"audit"})
+	assert.NoError(t, err, "Planning prune should return no error: %v", err)
+	assert.NoError(t, c.Prune(Config{}, changes), "Pruning audit devices should return no error")
+	assert.Empty(t, f.mounts("sys/audit"), "Audit devices should be disabled after prune")
+}
+
+func (vsc *vaultServerConfigTestSuite) TestVCClient_Audit() {
+	dir, err := ioutil.TempDir("", "audit")
+	assert.NoError(vsc.T(), err, "Creating temp dir should not return an error: %v", err)
+	defer os.RemoveAll(dir)
+
+	conf, err := ParseConfig([]byte(fmt.Sprintf(`
+audit "file" {
+  type = "file"
+  description = "Audit log"
+  options {
+    file_path = "%s"
+  }
+}`, filepath.Join(dir, "audit.log"))))
+	assert.NoError(vsc.T(), err, "Parsing config should not return an error: %v", err)
+	_, err = vsc.vtc.Apply(context.Background(), conf)
+	assert.NoError(vsc.T(), err, "Apply should not return an error: %v", err)
+
+	audit, err := vsc.vtc.Sys().ListAudit()
+	assert.NoError(vsc.T(), err, "Listing audit devices should not return an error: %v", err)
+	assert.Contains(vsc.T(), audit, "file/", "Audit device should be enabled")
+
+	// applying again should leave the device enabled rather than disabling
+	// and enabling it, which would give it a new accessor
+	before, err := vsc.vtc.Logical().Read("sys/audit")
+	assert.NoError(vsc.T(), err, "Reading audit devices should not return an error: %v", err)
+	report := applyAndPlan(vsc.T(), vsc.vtc, conf, "audit")
+	for _, ch := range report.Changes {
+		if ch.Resource == "audit" {
+			assert.Equal(vsc.T(), ActionNoop, ch.Action, "Unchanged audit devices should not be enabled again")
+		}
+	}
+	after, err := vsc.vtc.Logical().Read("sys/audit")
+	assert.NoError(vsc.T(), err, "Reading audit devices should not return an error: %v", err)
+	assert.Equal(vsc.T(), before.Data["file/"], after.Data["file/"], "Audit device should not change when applied again")
+
+	changes, err := vsc.vtc.PlanPrune(Config{}, []string{"audit"})
+	assert.NoError(vsc.T(), err, "Planning prune should return no error: %v", err)
+	assert.NoError(vsc.T(), vsc.vtc.Prune(Config{}, changes), "Pruning audit devices should return no error")
+	audit, err = vsc.vtc.Sys().ListAudit()
+	assert.NoError(vsc.T(), err, "Listing audit devices should not return an error: %v", err)
+	assert.NotContains(vsc.T(), audit, "file/", "Audit device should be disabled after prune")
+}
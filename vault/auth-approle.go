@@ -0,0 +1,250 @@
+package vault
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/elliottsam/vault-config/crypto"
+)
+
+// AppRole configures the AppRole auth backend, it has no users or groups,
+// only roles
+type AppRole struct {
+	Name        string          `hcl:",key" validate:"optional"`
+	Path        string          `hcl:"path"`
+	Description string          `hcl:"description"`
+	Roles       []AppRoleRole   `hcl:"role"`
+	MountConfig AuthMountConfig `hcl:"mountconfig"`
+}
+
+// AppRoleRole is a role in the AppRole auth backend, Options are written
+// to the role e.g. token_policies, token_ttl, secret_id_bound_cidrs and
+// secret_id_num_uses. RoleID pins the role_id rather than letting Vault
+// generate one
+type AppRoleRole struct {
+	Name     string                 `hcl:",key"`
+	RoleID   string                 `hcl:"role_id"`
+	Options  map[string]interface{} `hcl:"options"`
+	SecretID *AppRoleSecretID       `hcl:"secret_id"`
+}
+
+// AppRoleSecretID generates a secret ID for a role when File does not
+// exist, the role_id and encrypted secret_id are written to File. Options
+// are passed to Vault when generating, e.g. metadata, cidr_list and ttl
+type AppRoleSecretID struct {
+	File    string                 `hcl:"file" validate:"required"`
+	Options map[string]interface{} `hcl:"options"`
+}
+
+func (a AppRole) GetType() string {
+	return "approle"
+}
+
+func (a AppRole) GetPath() string {
+	return authPath(a.Path, a.GetType())
+}
+
+func (a AppRole) Describe() string {
+	return a.Description
+}
+
+func (a AppRole) TuneMount(c *VCClient, path string) error {
+	return c.TuneMount(path, authTune(a))
+}
+
+func (a AppRole) WriteUsers(c *VCClient) error {
+	return nil
+}
+
+func (a AppRole) WriteGroups(c *VCClient) error {
+	return nil
+}
+
+// Configure writes the roles, the AppRole backend has no config
+func (a AppRole) Configure(c *VCClient) error {
+	for _, r := range a.roleResources() {
+		if err := r.Update(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a AppRole) getAuthConfig() map[string]interface{} {
+	return nil
+}
+
+func (a AppRole) getAuthMountConfig() map[string]interface{} {
+	return ConvertMapStringInterface(a.MountConfig)
+}
+
+func (a AppRole) getUsers() map[string]map[string]interface{} {
+	return nil
+}
+
+func (a AppRole) getGroups() map[string]map[string]interface{} {
+	return nil
+}
+
+func (a AppRole) userPath() string {
+	return ""
+}
+
+func (a AppRole) groupPath() string {
+	return ""
+}
+
+func (a AppRole) rolePath() string {
+	return fmt.Sprintf("%s/role", Path(a))
+}
+
+func (a AppRole) rolePaths() []string {
+	return []string{a.rolePath()}
+}
+
+func (a AppRole) roleResources() []Resource {
+	var out []Resource
+	for _, r := range a.Roles {
+		out = append(out, &appRoleResource{mount: a.GetPath(), path: fmt.Sprintf("%s/%s", a.rolePath(), r.Name), role: r})
+	}
+
+	return out
+}
+
+// appRoleResource is a role in the AppRole backend along with its pinned
+// role_id and generated secret ID
+type appRoleResource struct {
+	mount string
+	path  string
+	role  AppRoleRole
+}
+
+func (r *appRoleResource) Kind() string      { return "auth_role" }
+func (r *appRoleResource) ID() string        { return r.role.Name }
+func (r *appRoleResource) VaultPath() string { return r.path }
+
+func (r *appRoleResource) policies() []string { return policyNames(r.role.Options) }
+
+func (r *appRoleResource) Read(c *VCClient) (map[string]interface{}, bool, error) {
+	exists, err := c.AuthExist(r.mount)
+	if err != nil || !exists {
+		return nil, false, err
+	}
+	state, exists, err := c.readData(r.path)
+	if err != nil || !exists {
+		return state, exists, err
+	}
+	if r.role.RoleID != "" {
+		s, err := c.Logical().Read(fmt.Sprintf("%s/role-id", r.path))
+		if err != nil {
+			return nil, false, fmt.Errorf("Error reading role_id: %s\nError: %v", r.path, err)
+		}
+		if s != nil {
+			state["role_id"] = s.Data["role_id"]
+		}
+	}
+	// the secret ID is not read back from Vault, it is in sync if the file
+	// it is written to exists
+	if r.role.SecretID != nil {
+		if _, err := os.Stat(r.role.SecretID.File); err == nil {
+			state["secret_id"] = r.role.SecretID.File
+		}
+	}
+
+	return state, true, nil
+}
+
+func (r *appRoleResource) Diff(state map[string]interface{}, exists bool) Change {
+	desired := make(map[string]interface{})
+	for k, v := range r.role.Options {
+		desired[k] = v
+	}
+	if r.role.RoleID != "" {
+		desired["role_id"] = r.role.RoleID
+	}
+	if r.role.SecretID != nil {
+		desired["secret_id"] = r.role.SecretID.File
+	}
+
+	return diffResource(r.Kind(), r.ID(), r.path, desired, state, exists)
+}
+
+func (r *appRoleResource) Create(c *VCClient) error {
+	return r.Update(c)
+}
+
+func (r *appRoleResource) Update(c *VCClient) error {
+	if _, err := c.Logical().Write(r.path, r.role.Options); err != nil {
+		return fmt.Errorf("Error writing AppRole role: %s\nError: %v", r.role.Name, err)
+	}
+	if r.role.RoleID != "" {
+		data := map[string]interface{}{"role_id": r.role.RoleID}
+		if _, err := c.Logical().Write(fmt.Sprintf("%s/role-id", r.path), data); err != nil {
+			return fmt.Errorf("Error writing role_id for AppRole role: %s\nError: %v", r.role.Name, err)
+		}
+	}
+	if r.role.SecretID != nil {
+		if _, err := os.Stat(r.role.SecretID.File); os.IsNotExist(err) {
+			return r.generateSecretID(c)
+		}
+	}
+
+	return nil
+}
+
+func (r *appRoleResource) Delete(c *VCClient) error {
+	if _, err := c.Logical().Delete(r.path); err != nil {
+		return fmt.Errorf("Error deleting Vault path: %s\nError: %v", r.path, err)
+	}
+
+	return nil
+}
+
+// generateSecretID creates a secret ID for the role and writes it to the
+// secret ID file encrypted with the client's EncryptionKey
+func (r *appRoleResource) generateSecretID(c *VCClient) error {
+	if c.EncryptionKey == nil {
+		return fmt.Errorf("Error generating secret ID for AppRole role: %s\nError: no encryption key", r.role.Name)
+	}
+	s, err := c.Logical().Read(fmt.Sprintf("%s/role-id", r.path))
+	if err != nil || s == nil {
+		return fmt.Errorf("Error reading role_id for AppRole role: %s\nError: %v", r.role.Name, err)
+	}
+	roleID := fmt.Sprint(s.Data["role_id"])
+	s, err = c.Logical().Write(fmt.Sprintf("%s/secret-id", r.path), r.role.SecretID.Options)
+	if err != nil || s == nil {
+		return fmt.Errorf("Error generating secret ID for AppRole role: %s\nError: %v", r.role.Name, err)
+	}
+	secretID, err := crypto.EncryptString(fmt.Sprint(s.Data["secret_id"]), c.EncryptionKey)
+	if err != nil {
+		return fmt.Errorf("Error encrypting secret ID for AppRole role: %s\nError: %v", r.role.Name, err)
+	}
+
+	file := r.role.SecretID.File
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return fmt.Errorf("Error creating directory for secret ID file: %s\nError: %v", file, err)
+	}
+	data := fmt.Sprintf("role_id   = %q\nsecret_id = %q\n", roleID, secretID)
+	if err := ioutil.WriteFile(file, []byte(data), 0600); err != nil {
+		return fmt.Errorf("Error writing secret ID file: %s\nError: %v", file, err)
+	}
+
+	return nil
+}
+
+// GeneratesSecretIDs returns true if any AppRole roles generate secret IDs,
+// an encryption key is needed to write them out
+func (c Config) GeneratesSecretIDs() bool {
+	for _, a := range c.Auth.AppRole {
+		for _, r := range a.Roles {
+			if r.SecretID != nil {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,155 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/elliottsam/vault-config/crypto"
+	"github.com/hashicorp/hcl"
+	"github.com/stretchr/testify/assert"
+)
+
+const appRoleConfig = `
+policy "app1" {
+  rules = "path \"secret/app1/*\" { capabilities = [\"read\"] }"
+}
+
+auth {
+  approle {
+    description = "AppRole auth backend"
+    role "app1" {
+      role_id = "app1-role-id"
+      options {
+        token_policies = ["app1"]
+        token_ttl = "1h"
+        secret_id_bound_cidrs = ["10.0.0.0/8"]
+        secret_id_num_uses = 0
+      }
+      secret_id {
+        file = "creds/app1.vc"
+      }
+    }
+    role "app2" {
+      options {
+        token_policies = ["default"]
+      }
+    }
+  }
+}
+`
+
+func TestAppRole_Parse(t *testing.T) {
+	conf, err := ParseConfig([]byte(appRoleConfig))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	assert.NotNil(t, conf.Auth.AppRole, "AppRole should be decoded")
+	assert.True(t, conf.GeneratesSecretIDs(), "Config with a secret_id block should generate secret IDs")
+	assert.Empty(t, validationMessages(Validate([]ConfigFile{{Name: "approle.vc", Data: []byte(appRoleConfig)}})),
+		"AppRole config should be valid")
+}
+
+func TestAppRole_Order(t *testing.T) {
+	conf, err := ParseConfig([]byte(appRoleConfig))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	resources, err := conf.AllResources()
+	assert.NoError(t, err, "Sorting resources should return no error: %v", err)
+	order := refs(resources)
+	assert.NotContains(t, order, "auth_config.approle", "AppRole has no config to write")
+
+	tests := []struct {
+		before string
+		after  string
+	}{
+		{"auth.approle", "auth_role.app1"},
+		{"auth.approle", "auth_role.app2"},
+		{"policy.app1", "auth_role.app1"},
+	}
+	for _, tt := range tests {
+		assert.True(t, indexOf(order, tt.before) < indexOf(order, tt.after), "%s should be applied after %s", tt.after, tt.before)
+	}
+	assert.Equal(t, "auth/approle/role/app1", resources[indexOf(order, "auth_role.app1")].VaultPath(),
+		"Role should be written beneath the backend")
+}
+
+func TestAppRole_Apply(t *testing.T) {
+	f, c := newFakeVault(t)
+	defer f.Close()
+	generated := 0
+	f.handle("auth/approle/role/app1/secret-id", func(method string, body map[string]interface{}) map[string]interface{} {
+		generated++
+		return map[string]interface{}{"secret_id": fmt.Sprintf("secret-%d", generated)}
+	})
+	dir, err := ioutil.TempDir("", "approle")
+	assert.NoError(t, err, "Creating temp dir should not return an error: %v", err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "creds", "app1.vc")
+
+	conf, err := ParseConfig([]byte(appRoleConfig))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	conf.Auth.AppRole[0].Roles[0].SecretID.File = file
+	applyAndPlan(t, c, conf)
+
+	assert.Equal(t, []interface{}{"app1"}, f.get("auth/approle/role/app1")["token_policies"], "Role options should be written")
+	assert.Equal(t, "app1-role-id", f.get("auth/approle/role/app1/role-id")["role_id"], "role_id should be pinned")
+	b, err := ioutil.ReadFile(file)
+	assert.NoError(t, err, "Secret ID file should be written: %v", err)
+	var creds struct {
+		RoleID   string `hcl:"role_id"`
+		SecretID string `hcl:"secret_id"`
+	}
+	assert.NoError(t, hcl.Decode(&creds, string(b)), "Secret ID file should be HCL")
+	assert.Equal(t, "app1-role-id", creds.RoleID, "role_id should be written with the secret ID")
+	secretID, err := crypto.DecryptString(creds.SecretID, c.EncryptionKey)
+	assert.NoError(t, err, "Secret ID should be encrypted with the encryption key: %v", err)
+	assert.Equal(t, "secret-1", secretID, "The generated secret ID should be written")
+
+	// the secret ID is only generated while its file does not exist and a
+	// role_id changed in Vault is pinned again
+	_, err = c.Logical().Write("auth/approle/role/app1/role-id", map[string]interface{}{"role_id": "changed"})
+	assert.NoError(t, err, "Writing role_id should not return an error: %v", err)
+	applyAndPlan(t, c, conf)
+	assert.Equal(t, 1, generated, "Secret ID should not be generated again")
+	assert.Equal(t, "app1-role-id", f.get("auth/approle/role/app1/role-id")["role_id"], "role_id should be pinned again")
+}
+
+func TestAppRole_GenerateSecretID(t *testing.T) {
+	conf, err := ParseConfig([]byte(appRoleConfig))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	resources, err := conf.AllResources()
+	assert.NoError(t, err, "Sorting resources should return no error: %v", err)
+	r := resources[indexOf(refs(resources), "auth_role.app1")].(*appRoleResource)
+
+	err = r.generateSecretID(&VCClient{})
+	assert.Error(t, err, "Generating a secret ID without an encryption key should fail")
+}
+
+func (vsc *vaultServerConfigTestSuite) TestVCClient_AppRole() {
+	dir, err := ioutil.TempDir("", "approle")
+	assert.NoError(vsc.T(), err, "Creating temp dir should not return an error: %v", err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "app1.vc")
+
+	conf, err := ParseConfig([]byte(appRoleConfig))
+	assert.NoError(vsc.T(), err, "Parsing config should not return an error: %v", err)
+	conf.Auth.AppRole[0].Roles[0].SecretID.File = file
+
+	c := &VCClient{Client: vsc.vtc.Client, EncryptionKey: []byte("abcdefghijklmnopqrstuvwxyz012345")}
+	_, err = c.Apply(context.Background(), conf)
+	assert.NoError(vsc.T(), err, "Apply should not return an error: %v", err)
+
+	s, err := c.Logical().Read("auth/approle/role/app1/role-id")
+	assert.NoError(vsc.T(), err, "Reading role_id should not return an error: %v", err)
+	assert.Equal(vsc.T(), "app1-role-id", s.Data["role_id"], "role_id should be pinned")
+	b, err := ioutil.ReadFile(file)
+	assert.NoError(vsc.T(), err, "Secret ID file should be written: %v", err)
+	assert.Regexp(vsc.T(), `secret_id = "@encrypted_data\(`, string(b), "Secret ID should be encrypted")
+
+	// the secret ID is only generated while its file does not exist
+	applyAndPlan(vsc.T(), c, conf, "auth", "auth_role")
+	after, err := ioutil.ReadFile(file)
+	assert.NoError(vsc.T(), err, "Secret ID file should still exist: %v", err)
+	assert.Equal(vsc.T(), string(b), string(after), "Secret ID should not be generated again")
+}
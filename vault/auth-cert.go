@@ -0,0 +1,189 @@
+package vault
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// certExpiryWarning is how long before a certificate expires that a
+// warning is given
+const certExpiryWarning = 30 * 24 * time.Hour
+
+// Cert configures the TLS certificate auth backend
+type Cert struct {
+	Name         string                 `hcl:",key" validate:"optional"`
+	Path         string                 `hcl:"path"`
+	Description  string                 `hcl:"description"`
+	AuthConfig   map[string]interface{} `hcl:"authconfig"`
+	Certificates []CertEntry            `hcl:"certificate"`
+	MountConfig  AuthMountConfig        `hcl:"mountconfig"`
+}
+
+// CertEntry is a trusted certificate, File is a PEM file that is read into
+// the certificate option. Options are written with the certificate, e.g.
+// allowed_common_names, token_policies and token_ttl
+type CertEntry struct {
+	Name    string                 `hcl:",key"`
+	File    string                 `hcl:"file" validate:"required"`
+	Options map[string]interface{} `hcl:"options"`
+}
+
+func (c Cert) GetType() string {
+	return "cert"
+}
+
+func (c Cert) GetPath() string {
+	return authPath(c.Path, c.GetType())
+}
+
+func (c Cert) Describe() string {
+	return c.Description
+}
+
+func (c Cert) TuneMount(vc *VCClient, path string) error {
+	return vc.TuneMount(path, authTune(c))
+}
+
+func (c Cert) WriteUsers(vc *VCClient) error {
+	return nil
+}
+
+func (c Cert) WriteGroups(vc *VCClient) error {
+	return nil
+}
+
+// Configure writes the config and certificates of the backend
+func (c Cert) Configure(vc *VCClient) error {
+	if len(c.AuthConfig) > 0 {
+		path := fmt.Sprintf("%s/config", Path(c))
+		if _, err := vc.Logical().Write(path, c.AuthConfig); err != nil {
+			return fmt.Errorf("Error writing auth config: %v", err)
+		}
+	}
+	for _, r := range c.roleResources() {
+		if err := r.Update(vc); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadFiles reads the PEM file of each certificate into its options,
+// returning an error if the file does not contain a valid certificate
+func (c Cert) loadFiles() error {
+	for i, e := range c.Certificates {
+		b, err := ioutil.ReadFile(e.File)
+		if err != nil {
+			return fmt.Errorf("Error reading certificate: %s\nError: %v", e.Name, err)
+		}
+		if _, err := parseCertificates(b); err != nil {
+			return fmt.Errorf("Error reading certificate: %s (%s)\nError: %v", e.Name, e.File, err)
+		}
+		if c.Certificates[i].Options == nil {
+			c.Certificates[i].Options = make(map[string]interface{})
+		}
+		c.Certificates[i].Options["certificate"] = string(b)
+	}
+
+	return nil
+}
+
+// warnings returns a warning for each certificate that has expired or
+// expires soon
+func (c Cert) warnings() []string {
+	var out []string
+	for _, e := range c.Certificates {
+		data, ok := e.Options["certificate"].(string)
+		if !ok {
+			continue
+		}
+		certs, err := parseCertificates([]byte(data))
+		if err != nil {
+			continue
+		}
+		for _, cert := range certs {
+			switch left := time.Until(cert.NotAfter); {
+			case left <= 0:
+				out = append(out, fmt.Sprintf("Certificate %s (%s) expired on %s", e.Name, cert.Subject.CommonName, cert.NotAfter.Format("2006-01-02")))
+			case left < certExpiryWarning:
+				out = append(out, fmt.Sprintf("Certificate %s (%s) expires on %s", e.Name, cert.Subject.CommonName, cert.NotAfter.Format("2006-01-02")))
+			}
+		}
+	}
+
+	return out
+}
+
+// parseCertificates returns the certificates in PEM data
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("unexpected PEM block: %s", block.Type)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no PEM certificates found")
+	}
+
+	return certs, nil
+}
+
+func (c Cert) getAuthConfig() map[string]interface{} {
+	return c.AuthConfig
+}
+
+func (c Cert) getAuthMountConfig() map[string]interface{} {
+	return ConvertMapStringInterface(c.MountConfig)
+}
+
+func (c Cert) getUsers() map[string]map[string]interface{} {
+	return nil
+}
+
+func (c Cert) getGroups() map[string]map[string]interface{} {
+	return nil
+}
+
+func (c Cert) userPath() string {
+	return ""
+}
+
+func (c Cert) groupPath() string {
+	return ""
+}
+
+func (c Cert) rolePath() string {
+	return fmt.Sprintf("%s/certs", Path(c))
+}
+
+func (c Cert) rolePaths() []string {
+	return []string{c.rolePath()}
+}
+
+func (c Cert) getRoles() map[string]map[string]interface{} {
+	roles := make(map[string]map[string]interface{})
+	for _, v := range c.Certificates {
+		roles[fmt.Sprintf("%s/%s", c.rolePath(), v.Name)] = v.Options
+	}
+
+	return roles
+}
+
+func (c Cert) roleResources() []Resource {
+	return authEntryResources("auth_role", c, c.getRoles())
+}
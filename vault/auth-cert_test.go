@@ -0,0 +1,185 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const certConfig = `
+auth {
+  cert {
+    certificate "web" {
+      file = "%s"
+      options {
+        allowed_common_names = ["web.example.com"]
+        token_policies = ["web"]
+        token_ttl = "1h"
+      }
+    }
+    certificate "batch" {
+      file = "%s"
+    }
+  }
+}
+`
+
+// writeTestCertificates writes a web certificate valid for a year and a
+// batch certificate that expires at batchExpiry
+func writeTestCertificates(t *testing.T, dir string, batchExpiry time.Time) (string, string) {
+	web := filepath.Join(dir, "web.pem")
+	batch := filepath.Join(dir, "batch.pem")
+	assert.NoError(t, ioutil.WriteFile(web, testCertificate(t, "web-ca", time.Now().Add(365*24*time.Hour)), 0600))
+	assert.NoError(t, ioutil.WriteFile(batch, testCertificate(t, "batch-ca", batchExpiry), 0600))
+
+	return web, batch
+}
+
+func TestCert_File(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cert")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	web, batch := writeTestCertificates(t, dir, time.Now().Add(365*24*time.Hour))
+	invalid := filepath.Join(dir, "invalid.pem")
+	assert.NoError(t, ioutil.WriteFile(invalid, []byte("not a certificate"), 0600))
+
+	tests := []struct {
+		name  string
+		batch string
+		err   bool
+	}{
+		{name: "certificate", batch: batch},
+		{name: "invalid certificate", batch: invalid, err: true},
+		{name: "missing file", batch: filepath.Join(dir, "missing.pem"), err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf, err := ParseConfig([]byte(fmt.Sprintf(certConfig, web, tt.batch)))
+			if tt.err {
+				assert.Error(t, err, "Invalid certificate files should return an error")
+				return
+			}
+			assert.NoError(t, err, "Parsing config should return no error: %v", err)
+			b, _ := ioutil.ReadFile(web)
+			assert.Equal(t, string(b), conf.Auth.Cert[0].Certificates[0].Options["certificate"],
+				"Certificate should be read from the file")
+		})
+	}
+}
+
+func TestCert_Warnings(t *testing.T) {
+	tests := []struct {
+		name    string
+		expiry  time.Time
+		warning string
+	}{
+		{name: "valid", expiry: time.Now().Add(365 * 24 * time.Hour)},
+		{name: "expires soon", expiry: time.Now().Add(7 * 24 * time.Hour), warning: "Certificate batch (batch-ca) expires on"},
+		{name: "expired", expiry: time.Now().Add(-time.Hour), warning: "Certificate batch (batch-ca) expired on"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "cert")
+			if err != nil {
+				t.Fatalf("Error creating temp dir: %v", err)
+			}
+			defer os.RemoveAll(dir)
+			web, batch := writeTestCertificates(t, dir, tt.expiry)
+
+			conf, err := ParseConfig([]byte(fmt.Sprintf(certConfig, web, batch)))
+			assert.NoError(t, err, "Expiring certificates should not return an error: %v", err)
+			warnings := conf.Warnings()
+			if tt.warning == "" {
+				assert.Empty(t, warnings, "Valid certificates should not be warned about")
+				return
+			}
+			if assert.Len(t, warnings, 1, "Only the batch certificate should be warned about: %v", warnings) {
+				assert.True(t, strings.HasPrefix(warnings[0], tt.warning), "Warning should name the certificate: %v", warnings)
+			}
+		})
+	}
+}
+
+func TestCert_Resources(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cert")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	web, batch := writeTestCertificates(t, dir, time.Now().Add(365*24*time.Hour))
+
+	conf, err := ParseConfig([]byte(fmt.Sprintf(certConfig, web, batch)))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	resources, err := conf.AllResources()
+	assert.NoError(t, err, "Sorting resources should return no error: %v", err)
+	assert.Equal(t, []string{"auth.cert", "auth_role.batch", "auth_role.web"}, refs(resources), "Certificates should be applied after the backend")
+	assert.Equal(t, "auth/cert/certs/web", resources[2].VaultPath(), "Certificates should be written beneath the backend")
+}
+
+func TestCert_Apply(t *testing.T) {
+	f, c := newFakeVault(t)
+	defer f.Close()
+	dir, err := ioutil.TempDir("", "cert")
+	assert.NoError(t, err, "Creating temp dir should not return an error: %v", err)
+	defer os.RemoveAll(dir)
+	web, batch := writeTestCertificates(t, dir, time.Now().Add(365*24*time.Hour))
+
+	conf, err := ParseConfig([]byte(fmt.Sprintf(certConfig, web, batch)))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	applyAndPlan(t, c, conf)
+	for _, file := range []string{web, batch} {
+		b, err := ioutil.ReadFile(file)
+		assert.NoError(t, err, "Reading certificate should not return an error: %v", err)
+		name := strings.TrimSuffix(filepath.Base(file), ".pem")
+		assert.Equal(t, string(b), f.get("auth/cert/certs/" + name)["certificate"], "Certificate %s should be read from the file", name)
+	}
+	assert.Equal(t, []interface{}{"web.example.com"}, f.get("auth/cert/certs/web")["allowed_common_names"], "Certificate options should be written")
+
+	// a renewed certificate is planned and written
+	renewed := testCertificate(t, "batch-ca", time.Now().Add(2*365*24*time.Hour))
+	assert.NoError(t, ioutil.WriteFile(batch, renewed, 0600), "Writing certificate should not return an error")
+	conf, err = ParseConfig([]byte(fmt.Sprintf(certConfig, web, batch)))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	p, err := c.Plan(conf)
+	assert.NoError(t, err, "Plan should not return an error: %v", err)
+	var changed []string
+	for _, ch := range p.Changes {
+		if ch.Action != ActionNoop {
+			changed = append(changed, fmt.Sprintf("%s.%s", ch.Resource, ch.Name))
+		}
+	}
+	assert.Equal(t, []string{"auth_role.batch"}, changed, "Only the renewed certificate should be planned")
+	applyAndPlan(t, c, conf)
+	assert.Equal(t, string(renewed), f.get("auth/cert/certs/batch")["certificate"], "The renewed certificate should be written")
+}
+
+func (vsc *vaultServerConfigTestSuite) TestVCClient_Cert() {
+	dir, err := ioutil.TempDir("", "cert")
+	assert.NoError(vsc.T(), err, "Creating temp dir should not return an error: %v", err)
+	defer os.RemoveAll(dir)
+	web, batch := filepath.Join(dir, "web.pem"), filepath.Join(dir, "batch.pem")
+	cert := testCertificate(vsc.T(), "web-ca", time.Now().Add(24*time.Hour))
+	assert.NoError(vsc.T(), ioutil.WriteFile(web, cert, 0600), "Writing certificate should not return an error")
+	assert.NoError(vsc.T(), ioutil.WriteFile(batch, testCertificate(vsc.T(), "batch-ca", time.Now().Add(24*time.Hour)), 0600),
+		"Writing certificate should not return an error")
+
+	conf, err := ParseConfig([]byte(fmt.Sprintf(certConfig, web, batch)))
+	assert.NoError(vsc.T(), err, "Parsing config should not return an error: %v", err)
+	_, err = vsc.vtc.Apply(context.Background(), conf)
+	assert.NoError(vsc.T(), err, "Apply should not return an error: %v", err)
+
+	s, err := vsc.vtc.Logical().Read("auth/cert/certs/web")
+	assert.NoError(vsc.T(), err, "Reading certificate should not return an error: %v", err)
+	assert.Equal(vsc.T(), string(cert), s.Data["certificate"], "Certificate should be read from the file")
+
+	applyAndPlan(vsc.T(), vsc.vtc, conf, "auth", "auth_role")
+}
@@ -0,0 +1,129 @@
+package vault
+
+import (
+	"fmt"
+	"sort"
+)
+
+func init() {
+	Register(ResourceType{Name: "generic_auth", New: func() Block { return &GenericAuth{} }})
+}
+
+// GenericAuth configures an auth backend of any type, such as okta, radius
+// or a plugin, that has no block of its own. Config is written to
+// <path>/config and Entries map a path beneath the backend, e.g. users or
+// role, to the entries written there keyed by name
+type GenericAuth struct {
+	Path        string                 `hcl:",key"`
+	Type        string                 `hcl:"type" validate:"required"`
+	Description string                 `hcl:"description"`
+	Tune        map[string]interface{} `hcl:"tune"`
+	Config      map[string]interface{} `hcl:"config"`
+	Entries     map[string]interface{} `hcl:"entries"`
+}
+
+func (g *GenericAuth) Kind() string { return "generic_auth" }
+func (g *GenericAuth) ID() string   { return g.Path }
+
+func (g GenericAuth) GetType() string {
+	return g.Type
+}
+
+func (g GenericAuth) GetPath() string {
+	return authPath(g.Path, g.GetType())
+}
+
+func (g GenericAuth) Describe() string {
+	return g.Description
+}
+
+func (g GenericAuth) TuneMount(c *VCClient, path string) error {
+	return c.TuneMount(path, authTune(g))
+}
+
+func (g GenericAuth) WriteUsers(c *VCClient) error {
+	return nil
+}
+
+func (g GenericAuth) WriteGroups(c *VCClient) error {
+	return nil
+}
+
+// Configure writes the config and entries of the backend
+func (g GenericAuth) Configure(c *VCClient) error {
+	if len(g.Config) > 0 {
+		path := fmt.Sprintf("%s/config", Path(g))
+		if _, err := c.Logical().Write(path, g.Config); err != nil {
+			return fmt.Errorf("Error writing auth config: %v", err)
+		}
+	}
+	for _, r := range g.roleResources() {
+		if err := r.Update(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (g GenericAuth) getAuthConfig() map[string]interface{} {
+	return g.Config
+}
+
+func (g GenericAuth) getAuthMountConfig() map[string]interface{} {
+	tune := make(map[string]interface{}, len(g.Tune))
+	for k, v := range g.Tune {
+		tune[k] = v
+	}
+
+	return tune
+}
+
+func (g GenericAuth) getUsers() map[string]map[string]interface{} {
+	return nil
+}
+
+func (g GenericAuth) getGroups() map[string]map[string]interface{} {
+	return nil
+}
+
+func (g GenericAuth) userPath() string {
+	return ""
+}
+
+func (g GenericAuth) groupPath() string {
+	return ""
+}
+
+func (g GenericAuth) rolePaths() []string {
+	var paths []string
+	for p := range g.Entries {
+		paths = append(paths, fmt.Sprintf("%s/%s", Path(g), p))
+	}
+	sort.Strings(paths)
+
+	return paths
+}
+
+// getEntries returns the options of each entry keyed by the path it is
+// written to, HCL decodes each entries block as a list of maps
+func (g GenericAuth) getEntries() map[string]map[string]interface{} {
+	entries := make(map[string]map[string]interface{})
+	for p, v := range g.Entries {
+		for _, block := range objectMaps(v) {
+			for name, options := range block {
+				var opts map[string]interface{}
+				for _, o := range objectMaps(options) {
+					opts = flattenOptions(o)
+				}
+				entries[fmt.Sprintf("%s/%s/%s", Path(g), p, name)] = opts
+			}
+		}
+	}
+
+	return entries
+}
+
+func (g GenericAuth) roleResources() []Resource {
+	return authEntryResources("auth_role", g, g.getEntries())
+}
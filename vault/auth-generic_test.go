@@ -0,0 +1,125 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const genericAuthConfig = `
+generic_auth "okta" {
+  type = "okta"
+  description = "Okta SSO"
+  tune {
+    default_lease_ttl = "1h"
+  }
+  config {
+    org_name = "example"
+  }
+  entries "groups" {
+    "admins" = {
+      policies = ["default"]
+    }
+  }
+  entries "users" {
+    alice = {
+      groups = ["admins"]
+    }
+  }
+}
+`
+
+func TestGenericAuth_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   []string
+	}{
+		{name: "valid", config: genericAuthConfig},
+		{
+			name: "missing type",
+			config: `generic_auth "okta" {
+  description = "missing type"
+}`,
+			want: []string{"okta.vc:1:1: generic_auth is missing required field: type"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, validationMessages(Validate([]ConfigFile{{Name: "okta.vc", Data: []byte(tt.config)}})),
+				"Generic auth backends should require a type")
+		})
+	}
+}
+
+func TestGenericAuth(t *testing.T) {
+	parsed, err := ParseConfig([]byte(genericAuthConfig))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	// plans are saved as JSON, entries should still be read after decoding
+	b, err := json.Marshal(parsed)
+	assert.NoError(t, err, "Encoding config should return no error: %v", err)
+	var decoded Config
+	assert.NoError(t, json.Unmarshal(b, &decoded), "Decoding config should return no error")
+
+	tests := []struct {
+		name string
+		conf Config
+	}{
+		{"parsed", parsed},
+		{"decoded from a saved plan", decoded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resources, err := tt.conf.AllResources()
+			assert.NoError(t, err, "Sorting resources should return no error: %v", err)
+			paths := make(map[string]string)
+			for _, r := range resources {
+				paths[resourceRef(r)] = r.VaultPath()
+			}
+			assert.Equal(t, map[string]string{
+				"auth.okta":        "auth/okta",
+				"auth_config.okta": "auth/okta/config",
+				"auth_role.admins": "auth/okta/groups/admins",
+				"auth_role.alice":  "auth/okta/users/alice",
+			}, paths, "The backend, config and entries should be configured")
+			g := tt.conf.Resources[0].(*GenericAuth)
+			assert.Equal(t, []string{"auth/okta/groups", "auth/okta/users"}, g.rolePaths(), "Entries should be listed from every path")
+			assert.Equal(t, map[string]interface{}{"groups": []interface{}{"admins"}}, g.getEntries()["auth/okta/users/alice"],
+				"Entry options should be maps")
+		})
+	}
+}
+
+func TestGenericAuth_Apply(t *testing.T) {
+	f, c := newFakeVault(t)
+	defer f.Close()
+	conf, err := ParseConfig([]byte(genericAuthConfig))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	applyAndPlan(t, c, conf)
+
+	mount := f.get("sys/auth/okta")
+	assert.Equal(t, "okta", mount["type"], "The backend should be enabled with its type")
+	assert.Equal(t, "Okta SSO", mount["description"], "The backend should be enabled with its description")
+	assert.Equal(t, 3600, mount["config"].(map[string]interface{})["default_lease_ttl"], "The backend should be tuned")
+	assert.Equal(t, "example", f.get("auth/okta/config")["org_name"], "Config should be written")
+	assert.Equal(t, []interface{}{"default"}, f.get("auth/okta/groups/admins")["policies"], "Entries should be written beneath their path")
+	assert.Equal(t, []interface{}{"admins"}, f.get("auth/okta/users/alice")["groups"], "Entries should be written beneath their path")
+}
+
+func (vsc *vaultServerConfigTestSuite) TestVCClient_GenericAuth() {
+	conf, err := ParseConfig([]byte(genericAuthConfig))
+	assert.NoError(vsc.T(), err, "Parsing config should not return an error: %v", err)
+	_, err = vsc.vtc.Apply(context.Background(), conf)
+	assert.NoError(vsc.T(), err, "Apply should not return an error: %v", err)
+
+	exists, err := vsc.vtc.AuthExist("okta")
+	assert.NoError(vsc.T(), err, "AuthExist should not return an error: %v", err)
+	assert.True(vsc.T(), exists, "Generic auth backend should be enabled")
+	s, err := vsc.vtc.Logical().Read("auth/okta/groups/admins")
+	assert.NoError(vsc.T(), err, "Reading entry should not return an error: %v", err)
+	assert.NotNil(vsc.T(), s, "Entries should be written beneath the backend")
+
+	applyAndPlan(vsc.T(), vsc.vtc, conf, "auth", "auth_config", "auth_role")
+}
@@ -3,30 +3,68 @@ package vault
 import (
 	"fmt"
 
-	"github.com/fatih/structs"
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/hcl/hcl/token"
 )
 
 type Github struct {
-	Description string `hcl:"description"`
-	Users       []struct {
-		Name    string                 `hcl:",key"`
-		Options map[string]interface{} `hcl:"options"`
-	} `hcl:"users,ommitempty"`
-	Groups []struct {
-		Name    string                 `hcl:"name"`
-		Options map[string]interface{} `hcl:"options"`
-	} `hcl:"teams,ommitempty"`
-	MountConfig struct {
-		DefaultLeaseTTL string `hcl:"default_lease_ttl"`
-		MaxLeaseTTL     string `hcl:"max_lease_ttl"`
-	} `hcl:"mountconfig"`
-	AuthConfig map[string]interface{} `hcl:"authconfig"`
+	Name        string                 `hcl:",key" validate:"optional"`
+	Path        string                 `hcl:"path"`
+	Description string                 `hcl:"description"`
+	Users       []AuthEntry            `hcl:"users,ommitempty"`
+	Groups      []GithubTeam           `hcl:"teams,ommitempty"`
+	MountConfig AuthMountConfig        `hcl:"mountconfig"`
+	AuthConfig  map[string]interface{} `hcl:"authconfig"`
+}
+
+// GithubTeam maps a Github team to policies, the team name is the block
+// key, e.g. teams "admins" {, or TeamName, the name attribute of an unnamed
+// teams block as used by earlier versions
+type GithubTeam struct {
+	Name     string                 `hcl:",key" validate:"optional"`
+	TeamName string                 `hcl:"name"`
+	Options  map[string]interface{} `hcl:"options"`
+}
+
+// decodeObject decodes the teams of the backend, HCL splits unnamed blocks
+// into a block per field when decoding them into a struct with a key
+func (g *Github) decodeObject(item *ast.ObjectItem) error {
+	obj, ok := item.Val.(*ast.ObjectType)
+	if !ok {
+		return nil
+	}
+	g.Groups = nil
+	for _, i := range obj.List.Filter("teams").Items {
+		// unnamed blocks are given an empty key, they are named by their
+		// name attribute
+		if len(i.Keys) == 0 {
+			i = &ast.ObjectItem{Keys: []*ast.ObjectKey{{Token: token.Token{Type: token.STRING, Text: `""`}}}, Val: i.Val}
+		}
+		var t GithubTeam
+		if err := hcl.DecodeObject(&t, i); err != nil {
+			return fmt.Errorf("Error decoding Github team: %v", err)
+		}
+		if t.Name == "" {
+			t.Name = t.TeamName
+		}
+		if t.Name == "" {
+			return fmt.Errorf("Error decoding Github team: teams blocks should be named, e.g. teams \"name\" {")
+		}
+		g.Groups = append(g.Groups, t)
+	}
+
+	return nil
 }
 
 func (g Github) GetType() string {
 	return "github"
 }
 
+func (g Github) GetPath() string {
+	return authPath(g.Path, g.GetType())
+}
+
 func (g Github) Describe() string {
 	return g.Description
 }
@@ -36,11 +74,11 @@ func (g Github) AConfig() map[string]interface{} {
 }
 
 func (g Github) TuneMount(c *VCClient, path string) error {
-	return c.TuneMount(path, structs.Map(g.MountConfig))
+	return c.TuneMount(path, authTune(g))
 }
 
 func (g Github) WriteUsers(c *VCClient) error {
-	userPath := fmt.Sprintf("%s/map/users", Path(g))
+	userPath := g.userPath()
 
 	for _, v := range g.Users {
 		path := fmt.Sprintf("%s/%s", userPath, v.Name)
@@ -54,7 +92,7 @@ func (g Github) WriteUsers(c *VCClient) error {
 }
 
 func (g Github) WriteGroups(c *VCClient) error {
-	groupPath := fmt.Sprintf("%s/map/teams", Path(g))
+	groupPath := g.groupPath()
 
 	for _, v := range g.Groups {
 		path := fmt.Sprintf("%s/%s", groupPath, v.Name)
@@ -84,3 +122,29 @@ func (g Github) getAuthConfig() map[string]interface{} {
 func (g Github) getAuthMountConfig() map[string]interface{} {
 	return ConvertMapStringInterface(g.MountConfig)
 }
+
+func (g Github) getUsers() map[string]map[string]interface{} {
+	users := make(map[string]map[string]interface{})
+	for _, v := range g.Users {
+		users[fmt.Sprintf("%s/%s", g.userPath(), v.Name)] = v.Options
+	}
+
+	return users
+}
+
+func (g Github) getGroups() map[string]map[string]interface{} {
+	groups := make(map[string]map[string]interface{})
+	for _, v := range g.Groups {
+		groups[fmt.Sprintf("%s/%s", g.groupPath(), v.Name)] = v.Options
+	}
+
+	return groups
+}
+
+func (g Github) userPath() string {
+	return fmt.Sprintf("%s/map/users", Path(g))
+}
+
+func (g Github) groupPath() string {
+	return fmt.Sprintf("%s/map/teams", Path(g))
+}
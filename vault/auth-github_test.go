@@ -0,0 +1,64 @@
+package vault
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGithubTeams(t *testing.T) {
+	tests := []struct {
+		name  string
+		teams string
+		want  []string
+		err   bool
+	}{
+		{
+			name:  "named blocks",
+			teams: `teams "admins" { options { policy = "admin" } }` + "\n" + `teams "devs" { options { policy = "dev" } }`,
+			want:  []string{"admins", "devs"},
+		},
+		{
+			name:  "name attribute",
+			teams: `teams { name = "admins" options { policy = "admin" } }` + "\n" + `teams { name = "devs" options { policy = "dev" } }`,
+			want:  []string{"admins", "devs"},
+		},
+		{
+			name:  "both forms",
+			teams: `teams "admins" { options { policy = "admin" } }` + "\n" + `teams { name = "devs" options { policy = "dev" } }`,
+			want:  []string{"admins", "devs"},
+		},
+		{
+			name:  "no name",
+			teams: `teams { options { policy = "admin" } }`,
+			err:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := fmt.Sprintf("auth {\n  github {\n%s\n  }\n}\n", tt.teams)
+			conf, err := ParseConfig([]byte(config))
+			if tt.err {
+				assert.Error(t, err, "Teams without a name should return an error")
+				return
+			}
+			assert.NoError(t, err, "Parsing config should return no error: %v", err)
+			assert.Empty(t, validationMessages(Validate([]ConfigFile{{Name: "github.vc", Data: []byte(config)}})),
+				"Github config should be valid")
+			g := conf.Auth.Github[0]
+			var names []string
+			for _, team := range g.Groups {
+				names = append(names, team.Name)
+			}
+			assert.Equal(t, tt.want, names, "Teams should be named")
+			for _, team := range g.Groups {
+				if team.TeamName != "" {
+					assert.Equal(t, team.TeamName, team.Name, "Teams should be named by their name attribute")
+				}
+			}
+			assert.Equal(t, map[string]interface{}{"policy": "admin"}, g.getGroups()[g.groupPath()+"/admins"],
+				"Team options should be written to the team's path")
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package vault
+
+import "fmt"
+
+// JWT configures the JWT or OIDC auth backend, both are the same plugin and
+// are declared with a jwt or oidc block. authconfig takes the options of
+// auth/<type>/config, e.g. oidc_discovery_url, jwks_url or
+// jwt_validation_pubkeys and default_role
+type JWT struct {
+	Name        string                 `hcl:",key" validate:"optional"`
+	Path        string                 `hcl:"path"`
+	Description string                 `hcl:"description"`
+	AuthConfig  map[string]interface{} `hcl:"authconfig"`
+	Roles       []AuthEntry            `hcl:"role"`
+	MountConfig AuthMountConfig        `hcl:"mountconfig"`
+
+	// oidc is set for backends declared with an oidc block
+	oidc bool
+}
+
+func (j JWT) GetType() string {
+	if j.oidc {
+		return "oidc"
+	}
+
+	return "jwt"
+}
+
+func (j JWT) GetPath() string {
+	return authPath(j.Path, j.GetType())
+}
+
+func (j JWT) Describe() string {
+	return j.Description
+}
+
+func (j JWT) TuneMount(c *VCClient, path string) error {
+	return c.TuneMount(path, authTune(j))
+}
+
+func (j JWT) WriteUsers(c *VCClient) error {
+	return nil
+}
+
+func (j JWT) WriteGroups(c *VCClient) error {
+	return nil
+}
+
+// Configure writes the config and roles of the backend
+func (j JWT) Configure(c *VCClient) error {
+	path := fmt.Sprintf("%s/config", Path(j))
+	if _, err := c.Logical().Write(path, j.AuthConfig); err != nil {
+		return fmt.Errorf("Error writing auth config: %v", err)
+	}
+	for _, r := range j.roleResources() {
+		if err := r.Update(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (j JWT) getAuthConfig() map[string]interface{} {
+	return j.AuthConfig
+}
+
+func (j JWT) getAuthMountConfig() map[string]interface{} {
+	return ConvertMapStringInterface(j.MountConfig)
+}
+
+func (j JWT) getUsers() map[string]map[string]interface{} {
+	return nil
+}
+
+func (j JWT) getGroups() map[string]map[string]interface{} {
+	return nil
+}
+
+func (j JWT) userPath() string {
+	return ""
+}
+
+func (j JWT) groupPath() string {
+	return ""
+}
+
+func (j JWT) rolePath() string {
+	return fmt.Sprintf("%s/role", Path(j))
+}
+
+func (j JWT) rolePaths() []string {
+	return []string{j.rolePath()}
+}
+
+// getRoles returns the roles with nested blocks such as bound_claims and
+// claim_mappings converted to maps
+func (j JWT) getRoles() map[string]map[string]interface{} {
+	roles := make(map[string]map[string]interface{})
+	for _, v := range j.Roles {
+		roles[fmt.Sprintf("%s/%s", j.rolePath(), v.Name)] = flattenOptions(v.Options)
+	}
+
+	return roles
+}
+
+func (j JWT) roleResources() []Resource {
+	return authEntryResources("auth_role", j, j.getRoles())
+}
@@ -0,0 +1,165 @@
+package vault
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const jwtConfig = `
+auth {
+  jwt {
+    description = "CI pipelines"
+    authconfig {
+      jwks_url = "https://gitlab.example.com/-/jwks"
+      bound_issuer = "https://gitlab.example.com"
+      default_role = "ci"
+    }
+    role "ci" {
+      options {
+        role_type = "jwt"
+        user_claim = "project_path"
+        bound_audiences = ["https://vault.example.com"]
+        bound_claims {
+          namespace_path = ["platform", "apps"]
+        }
+        claim_mappings {
+          ref = "ref"
+        }
+        token_policies = ["ci"]
+        token_ttl = "10m"
+      }
+    }
+  }
+  oidc {
+    authconfig {
+      oidc_discovery_url = "https://login.example.com"
+      oidc_client_id = "vault"
+      oidc_client_secret = "secret"
+      default_role = "sso"
+    }
+    role "sso" {
+      options {
+        user_claim = "email"
+        groups_claim = "groups"
+        allowed_redirect_uris = ["https://vault.example.com/ui/vault/auth/oidc/oidc/callback"]
+        token_policies = ["default"]
+      }
+    }
+  }
+}
+`
+
+// testPublicKey returns a PEM public key for validating JWTs
+func testPublicKey(t assert.TestingT) string {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err, "Generating a key should not return an error")
+	der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	assert.NoError(t, err, "Marshalling a public key should not return an error")
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func TestJWT_Resources(t *testing.T) {
+	conf, err := ParseConfig([]byte(jwtConfig))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	assert.Empty(t, validationMessages(Validate([]ConfigFile{{Name: "jwt.vc", Data: []byte(jwtConfig)}})),
+		"JWT config should be valid")
+
+	resources, err := conf.AllResources()
+	assert.NoError(t, err, "Sorting resources should return no error: %v", err)
+	order := refs(resources)
+	tests := []struct {
+		resource string
+		path     string
+	}{
+		{"auth.jwt", "auth/jwt"},
+		{"auth.oidc", "auth/oidc"},
+		{"auth_config.jwt", "auth/jwt/config"},
+		{"auth_config.oidc", "auth/oidc/config"},
+		{"auth_role.ci", "auth/jwt/role/ci"},
+		{"auth_role.sso", "auth/oidc/role/sso"},
+	}
+	for _, tt := range tests {
+		i := indexOf(order, tt.resource)
+		if !assert.True(t, i >= 0, "JWT and OIDC backends should both be configured: %s", tt.resource) {
+			continue
+		}
+		assert.Equal(t, tt.path, resources[i].VaultPath(), "Resources should be written beneath their backend: %s", tt.resource)
+	}
+}
+
+func TestJWT_Apply(t *testing.T) {
+	f, c := newFakeVault(t)
+	defer f.Close()
+	conf, err := ParseConfig([]byte(jwtConfig))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	applyAndPlan(t, c, conf)
+
+	assert.Equal(t, "https://gitlab.example.com/-/jwks", f.get("auth/jwt/config")["jwks_url"], "JWT config should be written")
+	assert.Equal(t, "secret", f.get("auth/oidc/config")["oidc_client_secret"], "OIDC config should be written")
+	role := f.get("auth/jwt/role/ci")
+	assert.Equal(t, map[string]interface{}{"namespace_path": []interface{}{"platform", "apps"}}, role["bound_claims"],
+		"Bound claims should be written as a map")
+	assert.Equal(t, map[string]interface{}{"ref": "ref"}, role["claim_mappings"], "Claim mappings should be written as a map")
+
+	// bound claims match in any order, other changes made in Vault are
+	// written again
+	role["bound_claims"] = map[string]interface{}{"namespace_path": []interface{}{"apps", "platform"}}
+	role["claim_mappings"] = map[string]interface{}{"ref": "ref_type"}
+	p, err := c.Plan(conf)
+	assert.NoError(t, err, "Plan should not return an error: %v", err)
+	var changed []string
+	for _, ch := range p.Changes {
+		for _, field := range ch.Fields {
+			changed = append(changed, fmt.Sprintf("%s.%s %s", ch.Resource, ch.Name, field.Name))
+		}
+	}
+	assert.Equal(t, []string{"auth_role.ci claim_mappings"}, changed, "Only the changed claim mappings should be planned")
+	applyAndPlan(t, c, conf)
+	assert.Equal(t, map[string]interface{}{"ref": "ref"}, f.get("auth/jwt/role/ci")["claim_mappings"], "Claim mappings should be written again")
+}
+
+func (vsc *vaultServerConfigTestSuite) TestVCClient_JWT() {
+	// OIDC discovery and JWKS URLs are fetched by Vault so the role is
+	// validated against a public key instead
+	conf, err := ParseConfig([]byte(fmt.Sprintf(`
+auth {
+  jwt {
+    authconfig {
+      jwt_validation_pubkeys = [%q]
+      bound_issuer = "https://gitlab.example.com"
+    }
+    role "ci" {
+      options {
+        role_type = "jwt"
+        user_claim = "project_path"
+        bound_audiences = ["https://vault.example.com"]
+        bound_claims {
+          namespace_path = ["platform", "apps"]
+        }
+        claim_mappings {
+          ref = "ref"
+        }
+        token_policies = ["ci"]
+      }
+    }
+  }
+}`, testPublicKey(vsc.T()))))
+	assert.NoError(vsc.T(), err, "Parsing config should not return an error: %v", err)
+	_, err = vsc.vtc.Apply(context.Background(), conf)
+	assert.NoError(vsc.T(), err, "Apply should not return an error: %v", err)
+
+	s, err := vsc.vtc.Logical().Read("auth/jwt/role/ci")
+	assert.NoError(vsc.T(), err, "Reading JWT role should not return an error: %v", err)
+	assert.Equal(vsc.T(), map[string]interface{}{"ref": "ref"}, s.Data["claim_mappings"], "Claim mappings should be written")
+
+	applyAndPlan(vsc.T(), vsc.vtc, conf, "auth", "auth_config", "auth_role")
+}
@@ -0,0 +1,118 @@
+package vault
+
+import (
+	"fmt"
+	"io/ioutil"
+)
+
+// Kubernetes configures the Kubernetes auth backend, authconfig takes the
+// options of auth/kubernetes/config, kubernetes_ca_cert_file can be used
+// in place of kubernetes_ca_cert to read the CA certificate from a file
+type Kubernetes struct {
+	Name        string                 `hcl:",key" validate:"optional"`
+	Path        string                 `hcl:"path"`
+	Description string                 `hcl:"description"`
+	AuthConfig  map[string]interface{} `hcl:"authconfig"`
+	Roles       []AuthEntry            `hcl:"role"`
+	MountConfig AuthMountConfig        `hcl:"mountconfig"`
+}
+
+func (k Kubernetes) GetType() string {
+	return "kubernetes"
+}
+
+func (k Kubernetes) GetPath() string {
+	return authPath(k.Path, k.GetType())
+}
+
+func (k Kubernetes) Describe() string {
+	return k.Description
+}
+
+func (k Kubernetes) TuneMount(c *VCClient, path string) error {
+	return c.TuneMount(path, authTune(k))
+}
+
+func (k Kubernetes) WriteUsers(c *VCClient) error {
+	return nil
+}
+
+func (k Kubernetes) WriteGroups(c *VCClient) error {
+	return nil
+}
+
+// Configure writes the config and roles of the backend
+func (k Kubernetes) Configure(c *VCClient) error {
+	path := fmt.Sprintf("%s/config", Path(k))
+	if _, err := c.Logical().Write(path, k.AuthConfig); err != nil {
+		return fmt.Errorf("Error writing auth config: %v", err)
+	}
+	for _, r := range k.roleResources() {
+		if err := r.Update(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadFiles replaces kubernetes_ca_cert_file with the contents of the file
+func (k Kubernetes) loadFiles() error {
+	file, ok := k.AuthConfig["kubernetes_ca_cert_file"]
+	if !ok {
+		return nil
+	}
+	b, err := ioutil.ReadFile(fmt.Sprint(file))
+	if err != nil {
+		return fmt.Errorf("Error reading Kubernetes CA certificate: %v", err)
+	}
+	delete(k.AuthConfig, "kubernetes_ca_cert_file")
+	k.AuthConfig["kubernetes_ca_cert"] = string(b)
+
+	return nil
+}
+
+func (k Kubernetes) getAuthConfig() map[string]interface{} {
+	return k.AuthConfig
+}
+
+func (k Kubernetes) getAuthMountConfig() map[string]interface{} {
+	return ConvertMapStringInterface(k.MountConfig)
+}
+
+func (k Kubernetes) getUsers() map[string]map[string]interface{} {
+	return nil
+}
+
+func (k Kubernetes) getGroups() map[string]map[string]interface{} {
+	return nil
+}
+
+func (k Kubernetes) userPath() string {
+	return ""
+}
+
+func (k Kubernetes) groupPath() string {
+	return ""
+}
+
+func (k Kubernetes) rolePath() string {
+	return fmt.Sprintf("%s/role", Path(k))
+}
+
+func (k Kubernetes) rolePaths() []string {
+	return []string{k.rolePath()}
+}
+
+func (k Kubernetes) getRoles() map[string]map[string]interface{} {
+	roles := make(map[string]map[string]interface{})
+	for _, v := range k.Roles {
+		roles[fmt.Sprintf("%s/%s", k.rolePath(), v.Name)] = v.Options
+	}
+
+	return roles
+}
+
+func (k Kubernetes) roleResources() []Resource {
+	return authEntryResources("auth_role", k, k.getRoles())
+}
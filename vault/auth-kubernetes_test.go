@@ -0,0 +1,196 @@
+package vault
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/elliottsam/vault-config/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+// testCertificate returns a self signed PEM certificate that expires at notAfter
+func testCertificate(t assert.TestingT, cn string, notAfter time.Time) []byte {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err, "Generating a key should not return an error")
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             notAfter.Add(-48 * time.Hour),
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &priv.PublicKey, priv)
+	assert.NoError(t, err, "Creating a certificate should not return an error")
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+const kubernetesConfig = `
+auth {
+  kubernetes {
+    description = "Kubernetes auth backend"
+    authconfig {
+      kubernetes_host = "https://kubernetes.default.svc"
+      kubernetes_ca_cert_file = "%s"
+      token_reviewer_jwt = "%s"
+    }
+    role "app1" {
+      options {
+        bound_service_account_names = ["app1"]
+        bound_service_account_namespaces = ["default"]
+        policies = ["app1"]
+        ttl = "1h"
+      }
+    }
+  }
+}
+`
+
+func TestKubernetes_CACertFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubernetes")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "ca.crt")
+	cert := testCertificate(t, "kubernetes", time.Now().Add(24*time.Hour))
+	assert.NoError(t, ioutil.WriteFile(file, cert, 0600))
+
+	tests := []struct {
+		name string
+		file string
+		err  bool
+	}{
+		{name: "existing file", file: file},
+		{name: "missing file", file: filepath.Join(dir, "missing.crt"), err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf, err := ParseConfig([]byte(fmt.Sprintf(kubernetesConfig, tt.file, "reviewer-jwt")))
+			if tt.err {
+				assert.Error(t, err, "A missing CA certificate file should return an error")
+				return
+			}
+			assert.NoError(t, err, "Parsing config should return no error: %v", err)
+			ac := conf.Auth.Kubernetes[0].AuthConfig
+			assert.Equal(t, string(cert), ac["kubernetes_ca_cert"], "CA certificate should be read from the file")
+			assert.NotContains(t, ac, "kubernetes_ca_cert_file", "The file name should not be written to Vault")
+		})
+	}
+}
+
+func TestKubernetes_Secrets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubernetes")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "ca.crt")
+	assert.NoError(t, ioutil.WriteFile(file, testCertificate(t, "kubernetes", time.Now().Add(24*time.Hour)), 0600))
+
+	key := []byte("abcdefghijklmnopqrstuvwxyz012345")
+	jwt, err := crypto.EncryptString("reviewer-jwt", key)
+	if err != nil {
+		t.Fatalf("Error encrypting JWT: %v", err)
+	}
+	conf, err := ParseConfig([]byte(fmt.Sprintf(kubernetesConfig, file, jwt)))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	assert.True(t, SecretsEncrypted(conf), "Encrypted token reviewer JWT should be found")
+	assert.NoError(t, conf.DecryptSecrets(key), "Decrypting should return no error")
+	assert.Equal(t, "reviewer-jwt", conf.Auth.Kubernetes[0].AuthConfig["token_reviewer_jwt"], "Token reviewer JWT should be decrypted")
+}
+
+func TestKubernetes_Resources(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubernetes")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "ca.crt")
+	assert.NoError(t, ioutil.WriteFile(file, testCertificate(t, "kubernetes", time.Now().Add(24*time.Hour)), 0600))
+
+	conf, err := ParseConfig([]byte(fmt.Sprintf(kubernetesConfig, file, "reviewer-jwt")))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	resources, err := conf.AllResources()
+	assert.NoError(t, err, "Sorting resources should return no error: %v", err)
+	assert.Equal(t, []string{"auth.kubernetes", "auth_config.kubernetes", "auth_role.app1"}, refs(resources),
+		"Config and roles should be applied after the auth backend")
+
+	tests := []struct {
+		resource int
+		path     string
+	}{
+		{1, "auth/kubernetes/config"},
+		{2, "auth/kubernetes/role/app1"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.path, resources[tt.resource].VaultPath(), "Config and roles should be written beneath the backend")
+	}
+}
+
+func TestKubernetes_Apply(t *testing.T) {
+	f, c := newFakeVault(t)
+	defer f.Close()
+	dir, err := ioutil.TempDir("", "kubernetes")
+	assert.NoError(t, err, "Creating temp dir should not return an error: %v", err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "ca.crt")
+	cert := testCertificate(t, "kubernetes", time.Now().Add(24*time.Hour))
+	assert.NoError(t, ioutil.WriteFile(file, cert, 0600), "Writing CA certificate should not return an error")
+
+	conf, err := ParseConfig([]byte(fmt.Sprintf(kubernetesConfig, file, "reviewer-jwt")))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	applyAndPlan(t, c, conf)
+	assert.Equal(t, map[string]interface{}{
+		"kubernetes_host":    "https://kubernetes.default.svc",
+		"kubernetes_ca_cert": string(cert),
+		"token_reviewer_jwt": "reviewer-jwt",
+	}, f.get("auth/kubernetes/config"), "Config should be written with the contents of the CA certificate file")
+	assert.Equal(t, []interface{}{"app1"}, f.get("auth/kubernetes/role/app1")["bound_service_account_names"], "Roles should be written")
+
+	// a renewed CA certificate is written to Vault
+	cert = testCertificate(t, "kubernetes", time.Now().Add(48*time.Hour))
+	assert.NoError(t, ioutil.WriteFile(file, cert, 0600), "Writing CA certificate should not return an error")
+	conf, err = ParseConfig([]byte(fmt.Sprintf(kubernetesConfig, file, "reviewer-jwt")))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	applyAndPlan(t, c, conf)
+	assert.Equal(t, string(cert), f.get("auth/kubernetes/config")["kubernetes_ca_cert"], "The renewed CA certificate should be written")
+}
+
+func (vsc *vaultServerConfigTestSuite) TestVCClient_Kubernetes() {
+	dir, err := ioutil.TempDir("", "kubernetes")
+	assert.NoError(vsc.T(), err, "Creating temp dir should not return an error: %v", err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "ca.crt")
+	cert := testCertificate(vsc.T(), "kubernetes", time.Now().Add(24*time.Hour))
+	assert.NoError(vsc.T(), ioutil.WriteFile(file, cert, 0600), "Writing CA certificate should not return an error")
+
+	conf, err := ParseConfig([]byte(fmt.Sprintf(kubernetesConfig, file, "reviewer-jwt")))
+	assert.NoError(vsc.T(), err, "Parsing config should not return an error: %v", err)
+	_, err = vsc.vtc.Apply(context.Background(), conf)
+	assert.NoError(vsc.T(), err, "Apply should not return an error: %v", err)
+
+	s, err := vsc.vtc.Logical().Read("auth/kubernetes/config")
+	assert.NoError(vsc.T(), err, "Reading Kubernetes config should not return an error: %v", err)
+	assert.Equal(vsc.T(), "https://kubernetes.default.svc", s.Data["kubernetes_host"], "kubernetes_host should be written")
+	assert.Equal(vsc.T(), string(cert), s.Data["kubernetes_ca_cert"], "CA certificate should be read from the file")
+	s, err = vsc.vtc.Logical().Read("auth/kubernetes/role/app1")
+	assert.NoError(vsc.T(), err, "Reading Kubernetes role should not return an error: %v", err)
+	assert.NotNil(vsc.T(), s, "Kubernetes role should be written")
+
+	applyAndPlan(vsc.T(), vsc.vtc, conf, "auth", "auth_config", "auth_role")
+}
@@ -1,42 +1,41 @@
 package vault
 
-import (
-	"fmt"
+import "fmt"
 
-	"github.com/fatih/structs"
-)
+// AuthEntry is a user or group mapping written beneath an auth backend
+type AuthEntry struct {
+	Name    string                 `hcl:",key"`
+	Options map[string]interface{} `hcl:"options"`
+}
 
 type Ldap struct {
+	Name        string                 `hcl:",key" validate:"optional"`
+	Path        string                 `hcl:"path"`
 	Description string                 `hcl:"description"`
 	AuthConfig  map[string]interface{} `hcl:"authconfig"`
-	Users       []struct {
-		Name    string                 `hcl:",key"`
-		Options map[string]interface{} `hcl:"options"`
-	} `hcl:"User"`
-	Groups []struct {
-		Name    string                 `hcl:",key"`
-		Options map[string]interface{} `hcl:"options"`
-	} `hcl:"group"`
-	MountConfig struct {
-		DefaultLeaseTTL string `hcl:"default_lease_ttl" mapstructure:"default_lease_ttl"`
-		MaxLeaseTTL     string `hcl:"max_lease_ttl" mapstructure:"max_lease_ttl"`
-	} `hcl:"mountconfig"`
+	Users       []AuthEntry            `hcl:"User"`
+	Groups      []AuthEntry            `hcl:"group"`
+	MountConfig AuthMountConfig        `hcl:"mountconfig"`
 }
 
 func (l Ldap) GetType() string {
 	return "ldap"
 }
 
+func (l Ldap) GetPath() string {
+	return authPath(l.Path, l.GetType())
+}
+
 func (l Ldap) Describe() string {
 	return l.Description
 }
 
 func (l Ldap) TuneMount(c *VCClient, path string) error {
-	return c.TuneMount(path, structs.Map(l.MountConfig))
+	return c.TuneMount(path, authTune(l))
 }
 
 func (l Ldap) WriteUsers(c *VCClient) error {
-	userPath := fmt.Sprintf("%s/users", Path(l))
+	userPath := l.userPath()
 	for _, v := range l.Users {
 		if v.Name != "" {
 			path := fmt.Sprintf("%s/%s", userPath, v.Name)
@@ -51,7 +50,7 @@ func (l Ldap) WriteUsers(c *VCClient) error {
 }
 
 func (l Ldap) WriteGroups(c *VCClient) error {
-	groupPath := fmt.Sprintf("%s/groups", Path(l))
+	groupPath := l.groupPath()
 
 	for _, v := range l.Groups {
 		path := fmt.Sprintf("%s/%s", groupPath, v.Name)
@@ -80,4 +79,32 @@ func (l Ldap) getAuthConfig() map[string]interface{} {
 
 func (l Ldap) getAuthMountConfig() map[string]interface{} {
 	return ConvertMapStringInterface(l.MountConfig)
-}
\ No newline at end of file
+}
+
+func (l Ldap) getUsers() map[string]map[string]interface{} {
+	users := make(map[string]map[string]interface{})
+	for _, v := range l.Users {
+		if v.Name != "" {
+			users[fmt.Sprintf("%s/%s", l.userPath(), v.Name)] = v.Options
+		}
+	}
+
+	return users
+}
+
+func (l Ldap) getGroups() map[string]map[string]interface{} {
+	groups := make(map[string]map[string]interface{})
+	for _, v := range l.Groups {
+		groups[fmt.Sprintf("%s/%s", l.groupPath(), v.Name)] = v.Options
+	}
+
+	return groups
+}
+
+func (l Ldap) userPath() string {
+	return fmt.Sprintf("%s/users", Path(l))
+}
+
+func (l Ldap) groupPath() string {
+	return fmt.Sprintf("%s/groups", Path(l))
+}
@@ -0,0 +1,77 @@
+package vault
+
+import "fmt"
+
+// Userpass configures the userpass auth backend, the password of each
+// user is set in its options and may be encrypted
+type Userpass struct {
+	Name        string          `hcl:",key" validate:"optional"`
+	Path        string          `hcl:"path"`
+	Description string          `hcl:"description"`
+	Users       []AuthEntry     `hcl:"user"`
+	MountConfig AuthMountConfig `hcl:"mountconfig"`
+}
+
+func (u Userpass) GetType() string {
+	return "userpass"
+}
+
+func (u Userpass) GetPath() string {
+	return authPath(u.Path, u.GetType())
+}
+
+func (u Userpass) Describe() string {
+	return u.Description
+}
+
+func (u Userpass) TuneMount(c *VCClient, path string) error {
+	return c.TuneMount(path, authTune(u))
+}
+
+func (u Userpass) WriteUsers(c *VCClient) error {
+	for path, options := range u.getUsers() {
+		if _, err := c.Logical().Write(path, options); err != nil {
+			return fmt.Errorf("Error writing value to Vault: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (u Userpass) WriteGroups(c *VCClient) error {
+	return nil
+}
+
+// Configure does nothing, the userpass backend has no config
+func (u Userpass) Configure(c *VCClient) error {
+	return nil
+}
+
+func (u Userpass) getAuthConfig() map[string]interface{} {
+	return nil
+}
+
+func (u Userpass) getAuthMountConfig() map[string]interface{} {
+	return ConvertMapStringInterface(u.MountConfig)
+}
+
+func (u Userpass) getUsers() map[string]map[string]interface{} {
+	users := make(map[string]map[string]interface{})
+	for _, v := range u.Users {
+		users[fmt.Sprintf("%s/%s", u.userPath(), v.Name)] = v.Options
+	}
+
+	return users
+}
+
+func (u Userpass) getGroups() map[string]map[string]interface{} {
+	return nil
+}
+
+func (u Userpass) userPath() string {
+	return fmt.Sprintf("%s/users", Path(u))
+}
+
+func (u Userpass) groupPath() string {
+	return ""
+}
@@ -0,0 +1,116 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/elliottsam/vault-config/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+const userpassConfig = `
+auth {
+  userpass {
+    description = "Break glass accounts"
+    user "breakglass" {
+      options {
+        password = "%s"
+        policies = ["admin"]
+        token_ttl = "30m"
+      }
+    }
+  }
+}
+`
+
+func TestUserpass_Secrets(t *testing.T) {
+	key := []byte("abcdefghijklmnopqrstuvwxyz012345")
+	password, err := crypto.EncryptString("hunter2", key)
+	if err != nil {
+		t.Fatalf("Error encrypting password: %v", err)
+	}
+
+	conf, err := ParseConfig([]byte(fmt.Sprintf(userpassConfig, password)))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	assert.True(t, SecretsEncrypted(conf), "Encrypted user passwords should be found")
+	assert.NoError(t, conf.DecryptSecrets(key), "Decrypting should return no error")
+	assert.Equal(t, "hunter2", conf.Auth.Userpass[0].Users[0].Options["password"], "Password should be decrypted")
+	assert.False(t, SecretsEncrypted(conf), "No encrypted values should remain after decrypting")
+
+	assert.NoError(t, conf.EncryptSensitive(key), "Encrypting should return no error")
+	assert.True(t, SecretsEncrypted(conf), "Passwords should be encrypted by EncryptSensitive")
+}
+
+func TestUserpass_Resources(t *testing.T) {
+	conf, err := ParseConfig([]byte(fmt.Sprintf(userpassConfig, "hunter2")))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	resources, err := conf.AllResources()
+	assert.NoError(t, err, "Sorting resources should return no error: %v", err)
+	assert.Equal(t, []string{"auth.userpass", "auth_user.breakglass"}, refs(resources), "Userpass has users but no config or groups")
+	assert.Equal(t, "auth/userpass/users/breakglass", resources[1].VaultPath(), "Users should be written beneath the backend")
+}
+
+func TestUserpass_Apply(t *testing.T) {
+	f, c := newFakeVault(t)
+	defer f.Close()
+	// Vault does not return passwords when users are read
+	var user map[string]interface{}
+	f.handle("auth/userpass/users/breakglass", func(method string, body map[string]interface{}) map[string]interface{} {
+		if method != http.MethodGet {
+			user = body
+			return map[string]interface{}{}
+		}
+		if user == nil {
+			return nil
+		}
+		out := make(map[string]interface{})
+		for k, v := range user {
+			if k != "password" {
+				out[k] = v
+			}
+		}
+		return out
+	})
+
+	conf, err := ParseConfig([]byte(fmt.Sprintf(userpassConfig, "hunter2")))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	applyAndPlan(t, c, conf)
+	assert.Equal(t, "Break glass accounts", f.get("sys/auth/userpass")["description"], "Backend should be enabled")
+	assert.Equal(t, "hunter2", user["password"], "Password should be written")
+	assert.Equal(t, []interface{}{"admin"}, user["policies"], "User options should be written")
+
+	// passwords cannot be compared so they are written on every apply
+	conf, err = ParseConfig([]byte(fmt.Sprintf(userpassConfig, "hunter3")))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	applyAndPlan(t, c, conf)
+	assert.Equal(t, "hunter3", user["password"], "Changed passwords should be written")
+
+	user["policies"] = []interface{}{"default"}
+	p, err := c.Plan(conf)
+	assert.NoError(t, err, "Plan should not return an error: %v", err)
+	var changed []string
+	for _, ch := range p.Changes {
+		for _, field := range ch.Fields {
+			changed = append(changed, fmt.Sprintf("%s.%s %s", ch.Resource, ch.Name, field.Name))
+		}
+	}
+	assert.Equal(t, []string{"auth_user.breakglass policies"}, changed, "Options changed in Vault should be planned")
+}
+
+func (vsc *vaultServerConfigTestSuite) TestVCClient_Userpass() {
+	conf, err := ParseConfig([]byte(fmt.Sprintf(userpassConfig, "hunter2")))
+	assert.NoError(vsc.T(), err, "Parsing config should not return an error: %v", err)
+	_, err = vsc.vtc.Apply(context.Background(), conf)
+	assert.NoError(vsc.T(), err, "Apply should not return an error: %v", err)
+
+	login := func() {
+		s, err := vsc.vtc.Logical().Write("auth/userpass/login/breakglass", map[string]interface{}{"password": "hunter2"})
+		assert.NoError(vsc.T(), err, "Logging in should not return an error: %v", err)
+		assert.NotNil(vsc.T(), s, "User should be able to log in with the configured password")
+	}
+	login()
+	applyAndPlan(vsc.T(), vsc.vtc, conf, "auth", "auth_user")
+	login()
+}
@@ -2,45 +2,223 @@ package vault
 
 import (
 	"fmt"
+	"reflect"
+	"sort"
 	"strings"
+
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
 )
 
+func init() {
+	Register(ResourceType{Name: "auth", FromConfig: authResources, List: listAuth, Protected: []string{"token"}})
+	Register(ResourceType{Name: "auth_config", FromConfig: authConfigResources})
+	Register(ResourceType{Name: "auth_user", FromConfig: authUserResources, List: listAuthUsers})
+	Register(ResourceType{Name: "auth_group", FromConfig: authGroupResources, List: listAuthGroups})
+	Register(ResourceType{Name: "auth_role", FromConfig: authRoleResources, List: listAuthRoles})
+}
+
 // AuthType defines an interface for dealing with Auth backends
 type AuthType interface {
 	Describe() string
 	GetType() string
+	// GetPath returns the path the backend is mounted at beneath auth/
+	GetPath() string
 	getAuthConfig() map[string]interface{}
 	getAuthMountConfig() map[string]interface{}
 	//AConfig() map[string]interface{}
+	getUsers() map[string]map[string]interface{}
+	getGroups() map[string]map[string]interface{}
+	userPath() string
+	groupPath() string
 	Configure(c *VCClient) error
 	TuneMount(c *VCClient, path string) error
 	WriteUsers(c *VCClient) error
 	WriteGroups(c *VCClient) error
 }
 
+// roleBackend is implemented by auth backends that manage roles, or other
+// entries, beneath one or more paths
+type roleBackend interface {
+	roleResources() []Resource
+	rolePaths() []string
+}
+
+// objectDecoder is implemented by auth backends with blocks HCL can not
+// decode on its own, it is called by ParseConfig once the backend is decoded
+type objectDecoder interface {
+	decodeObject(item *ast.ObjectItem) error
+}
+
+// fileLoader is implemented by auth backends and resources with options
+// that are read from files, such as certificates, the files are read by
+// ParseConfig
+type fileLoader interface {
+	loadFiles() error
+}
+
+// warner is implemented by auth backends that can warn about problems
+// that do not stop the configuration being applied
+type warner interface {
+	warnings() []string
+}
+
+// Warnings returns problems with the configuration that do not stop it
+// being applied, such as certificates that expire soon
+func (c Config) Warnings() []string {
+	var out []string
+	for _, a := range c.authBackends() {
+		if w, ok := a.(warner); ok {
+			out = append(out, w.warnings()...)
+		}
+	}
+
+	return out
+}
+
+// backends returns the auth backends that have been configured
+func (a Auth) backends() []AuthType {
+	var b []AuthType
+	for _, v := range a.Ldap {
+		b = append(b, v)
+	}
+	for _, v := range a.Github {
+		b = append(b, v)
+	}
+	for _, v := range a.AppRole {
+		b = append(b, v)
+	}
+	for _, v := range a.Userpass {
+		b = append(b, v)
+	}
+	for _, v := range a.Kubernetes {
+		b = append(b, v)
+	}
+	for _, v := range a.JWT {
+		b = append(b, v)
+	}
+	for _, v := range a.Cert {
+		b = append(b, v)
+	}
+	for _, v := range a.OIDC {
+		o := *v
+		o.oidc = true
+		b = append(b, o)
+	}
+
+	return b
+}
+
+// decodeAuth decodes the auth blocks of a configuration file, the backends
+// in an auth block can be named, e.g. ldap "corp" {, or not, e.g. ldap {,
+// which HCL can not decode into a list on its own
+func decodeAuth(root *ast.ObjectList) (Auth, error) {
+	var a Auth
+	v := reflect.ValueOf(&a).Elem()
+	fields := hclFields(v.Type())
+	for _, item := range root.Filter("auth").Items {
+		obj, ok := item.Val.(*ast.ObjectType)
+		if !ok {
+			return a, fmt.Errorf("Error decoding auth: auth should be a block")
+		}
+		for _, i := range obj.List.Items {
+			name := keyName(i.Keys[0])
+			f, ok := fields[strings.ToLower(name)]
+			if !ok {
+				continue
+			}
+			list := v.FieldByIndex(f.Index)
+			b := reflect.New(list.Type().Elem().Elem())
+			if err := hcl.DecodeObject(b.Interface(), i); err != nil {
+				return a, fmt.Errorf("Error decoding auth %s: %v", name, err)
+			}
+			// the key is decoded into the name, so unnamed blocks get the type
+			label := ""
+			if len(i.Keys) > 1 {
+				label = keyName(i.Keys[1])
+			}
+			b.Elem().FieldByName("Name").SetString(label)
+			if d, ok := b.Interface().(objectDecoder); ok {
+				if err := d.decodeObject(i); err != nil {
+					return a, err
+				}
+			}
+			list.Set(reflect.Append(list, b))
+		}
+	}
+
+	return a, nil
+}
+
+// authBackends returns the auth backends in the auth block along with
+// generic auth backends
+func (c Config) authBackends() []AuthType {
+	b := c.Auth.backends()
+	for _, r := range c.Resources {
+		if g, ok := r.(*GenericAuth); ok {
+			b = append(b, g)
+		}
+	}
+
+	return b
+}
+
+// authPath returns the path a backend is mounted at, backends are mounted
+// at the name of their type unless a path is configured
+func authPath(path, authType string) string {
+	if p := strings.Trim(path, "/"); p != "" {
+		return p
+	}
+
+	return authType
+}
+
+// AuthMountConfig holds the tuning options of an auth backend
+type AuthMountConfig struct {
+	DefaultLeaseTTL           string   `hcl:"default_lease_ttl" mapstructure:"default_lease_ttl"`
+	MaxLeaseTTL               string   `hcl:"max_lease_ttl" mapstructure:"max_lease_ttl"`
+	ListingVisibility         string   `hcl:"listing_visibility" mapstructure:"listing_visibility"`
+	AuditNonHMACRequestKeys   []string `hcl:"audit_non_hmac_request_keys" mapstructure:"audit_non_hmac_request_keys"`
+	AuditNonHMACResponseKeys  []string `hcl:"audit_non_hmac_response_keys" mapstructure:"audit_non_hmac_response_keys"`
+	PassthroughRequestHeaders []string `hcl:"passthrough_request_headers" mapstructure:"passthrough_request_headers"`
+	AllowedResponseHeaders    []string `hcl:"allowed_response_headers" mapstructure:"allowed_response_headers"`
+	TokenType                 string   `hcl:"token_type" mapstructure:"token_type"`
+}
+
+// authTune returns the tuning options of an auth backend along with its
+// description, which can also be changed by tuning
+func authTune(a AuthType) map[string]interface{} {
+	tune := a.getAuthMountConfig()
+	if a.Describe() != "" {
+		tune["description"] = a.Describe()
+	}
+
+	return tune
+}
+
 // AuthExist checks for the existance of an Auth mount
-func (c *VCClient) AuthExist(name string) bool {
-	auth, err := c.Sys().ListAuth()
+func (c *VCClient) AuthExist(name string) (bool, error) {
+	auth, err := c.currentAuth()
 	if err != nil {
-		return false
+		return false, err
 	}
 	for a := range auth {
 		if strings.TrimSuffix(a, "/") == name {
-			return true
+			return true, nil
 		}
 	}
 
-	return false
+	return false, nil
 }
 
 // Path will return the path of an Auth backend
 func Path(a AuthType) string {
-	return fmt.Sprintf("auth/%s", a.GetType())
+	return fmt.Sprintf("auth/%s", a.GetPath())
 }
 
 // AuthEnable enables an auth backend
 func (c *VCClient) AuthEnable(a AuthType) error {
-	if err := c.Sys().EnableAuth(a.GetType(), a.GetType(), a.Describe()); err != nil {
+	if err := c.Sys().EnableAuth(a.GetPath(), a.GetType(), a.Describe()); err != nil {
 		return err
 	}
 
@@ -67,7 +245,11 @@ func (c *VCClient) AuthConfigure(a AuthType) error {
 }
 
 func EnableAndConfigure(a AuthType, c *VCClient) error {
-	if !c.AuthExist(a.GetType()) {
+	exists, err := c.AuthExist(a.GetPath())
+	if err != nil {
+		return err
+	}
+	if !exists {
 		if err := c.AuthEnable(a); err != nil {
 			return fmt.Errorf("Error enabling auth mount: %v", err)
 		}
@@ -78,3 +260,234 @@ func EnableAndConfigure(a AuthType, c *VCClient) error {
 
 	return nil
 }
+
+// authResource is the mount of an auth backend, backends listed from the
+// server that are not in the configuration have no AuthType
+type authResource struct {
+	a    AuthType
+	name string
+}
+
+func authResources(conf Config) ([]Resource, error) {
+	var out []Resource
+	seen := make(map[string]bool)
+	for _, a := range conf.authBackends() {
+		if seen[a.GetPath()] {
+			return nil, fmt.Errorf("Auth backend declared more than once at path: %s, use path to mount it elsewhere", a.GetPath())
+		}
+		seen[a.GetPath()] = true
+		out = append(out, &authResource{a: a, name: a.GetPath()})
+	}
+
+	return out, nil
+}
+
+func listAuth(c *VCClient, conf Config) ([]Resource, error) {
+	auths, err := c.currentAuth()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(auths))
+	for k := range auths {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var out []Resource
+	for _, k := range keys {
+		out = append(out, &authResource{name: strings.TrimSuffix(k, "/")})
+	}
+
+	return out, nil
+}
+
+func (r *authResource) Kind() string      { return "auth" }
+func (r *authResource) ID() string        { return r.name }
+func (r *authResource) VaultPath() string { return fmt.Sprintf("auth/%s", r.name) }
+
+func (r *authResource) Read(c *VCClient) (map[string]interface{}, bool, error) {
+	auths, err := c.currentAuth()
+	if err != nil {
+		return nil, false, err
+	}
+	ao, ok := auths[fmt.Sprintf("%s/", r.name)]
+	if !ok {
+		return nil, false, nil
+	}
+
+	state := map[string]interface{}{
+		"description":       ao.Description,
+		"default_lease_ttl": ao.Config.DefaultLeaseTTL,
+		"max_lease_ttl":     ao.Config.MaxLeaseTTL,
+	}
+	// the tune endpoint returns every tuning option, such as
+	// listing_visibility, that the list of auth backends does not
+	s, err := c.Logical().Read(fmt.Sprintf("sys/%s/tune", r.VaultPath()))
+	if err != nil {
+		return nil, false, fmt.Errorf("Error reading auth tuning: %s\nError: %v", r.name, err)
+	}
+	if s != nil {
+		for k, v := range s.Data {
+			state[k] = v
+		}
+	}
+
+	return state, true, nil
+}
+
+func (r *authResource) Diff(state map[string]interface{}, exists bool) Change {
+	return diffResource(r.Kind(), r.ID(), r.VaultPath(), authTune(r.a), state, exists)
+}
+
+func (r *authResource) Create(c *VCClient) error {
+	if err := c.AuthEnable(r.a); err != nil {
+		return fmt.Errorf("Error enabling auth mount: %v", err)
+	}
+
+	return r.Update(c)
+}
+
+func (r *authResource) Update(c *VCClient) error {
+	return r.a.TuneMount(c, r.VaultPath())
+}
+
+func (r *authResource) Delete(c *VCClient) error {
+	return c.Sys().DisableAuth(r.name)
+}
+
+func authConfigResources(conf Config) ([]Resource, error) {
+	var out []Resource
+	for _, a := range conf.authBackends() {
+		if len(a.getAuthConfig()) == 0 {
+			continue
+		}
+		out = append(out, &pathResource{
+			kind:      "auth_config",
+			name:      a.GetPath(),
+			path:      fmt.Sprintf("%s/config", Path(a)),
+			data:      a.getAuthConfig(),
+			authMount: a.GetPath(),
+			writeOnly: true,
+		})
+	}
+
+	return out, nil
+}
+
+// authEntryResources converts the users or groups of an auth backend
+// into resources
+func authEntryResources(kind string, a AuthType, entries map[string]map[string]interface{}) []Resource {
+	paths := make([]string, 0, len(entries))
+	for p := range entries {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var out []Resource
+	for _, p := range paths {
+		out = append(out, &pathResource{
+			kind:      kind,
+			name:      p[strings.LastIndex(p, "/")+1:],
+			path:      p,
+			data:      entries[p],
+			authMount: a.GetPath(),
+			writeOnly: true,
+		})
+	}
+
+	return out
+}
+
+func authUserResources(conf Config) ([]Resource, error) {
+	var out []Resource
+	for _, a := range conf.authBackends() {
+		out = append(out, authEntryResources("auth_user", a, a.getUsers())...)
+	}
+
+	return out, nil
+}
+
+func authGroupResources(conf Config) ([]Resource, error) {
+	var out []Resource
+	for _, a := range conf.authBackends() {
+		out = append(out, authEntryResources("auth_group", a, a.getGroups())...)
+	}
+
+	return out, nil
+}
+
+// listAuthEntries lists the users or groups of the auth backends in the
+// configuration that are enabled
+func listAuthEntries(c *VCClient, conf Config, kind string) ([]Resource, error) {
+	var out []Resource
+	for _, a := range conf.authBackends() {
+		exists, err := c.AuthExist(a.GetPath())
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			continue
+		}
+		path := a.userPath()
+		if kind == "auth_group" {
+			path = a.groupPath()
+		}
+		if path == "" {
+			continue
+		}
+		r, err := c.listPathResources(kind, path)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r...)
+	}
+
+	return out, nil
+}
+
+func listAuthUsers(c *VCClient, conf Config) ([]Resource, error) {
+	return listAuthEntries(c, conf, "auth_user")
+}
+
+func listAuthGroups(c *VCClient, conf Config) ([]Resource, error) {
+	return listAuthEntries(c, conf, "auth_group")
+}
+
+func authRoleResources(conf Config) ([]Resource, error) {
+	var out []Resource
+	for _, a := range conf.authBackends() {
+		if rb, ok := a.(roleBackend); ok {
+			out = append(out, rb.roleResources()...)
+		}
+	}
+
+	return out, nil
+}
+
+// listAuthRoles lists the roles of the auth backends in the configuration
+// that are enabled
+func listAuthRoles(c *VCClient, conf Config) ([]Resource, error) {
+	var out []Resource
+	for _, a := range conf.authBackends() {
+		rb, ok := a.(roleBackend)
+		if !ok {
+			continue
+		}
+		exists, err := c.AuthExist(a.GetPath())
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			continue
+		}
+		for _, p := range rb.rolePaths() {
+			r, err := c.listPathResources("auth_role", p)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, r...)
+		}
+	}
+
+	return out, nil
+}
@@ -0,0 +1,238 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const authPathsConfig = `
+auth {
+  ldap "corp" {
+    path = "ldap-corp"
+    authconfig {
+      url = "ldaps://corp.example.com"
+    }
+    group "admins" {
+      options {
+        policies = "admin"
+      }
+    }
+  }
+  ldap "partner" {
+    path = "ldap-partner"
+    authconfig {
+      url = "ldaps://partner.example.com"
+    }
+  }
+  github {
+    authconfig {
+      organization = "example"
+    }
+  }
+}
+`
+
+func TestAuthPaths_Parse(t *testing.T) {
+	conf, err := ParseConfig([]byte(authPathsConfig))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	assert.Empty(t, validationMessages(Validate([]ConfigFile{{Name: "auth.vc", Data: []byte(authPathsConfig)}})),
+		"Named and unnamed backends should be valid")
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"named", conf.Auth.Ldap[0].Name, "corp"},
+		{"second named", conf.Auth.Ldap[1].Name, "partner"},
+		{"unnamed", conf.Auth.Github[0].Name, ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.got, "Backends should keep their block name: %s", tt.name)
+	}
+}
+
+func TestAuthPaths(t *testing.T) {
+	conf, err := ParseConfig([]byte(authPathsConfig))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	resources, err := conf.AllResources()
+	assert.NoError(t, err, "Sorting resources should return no error: %v", err)
+	paths := make(map[string]string)
+	for _, r := range resources {
+		paths[resourceRef(r)] = r.VaultPath()
+	}
+	assert.Equal(t, map[string]string{
+		"auth.ldap-corp":           "auth/ldap-corp",
+		"auth.ldap-partner":        "auth/ldap-partner",
+		"auth.github":              "auth/github",
+		"auth_config.ldap-corp":    "auth/ldap-corp/config",
+		"auth_config.ldap-partner": "auth/ldap-partner/config",
+		"auth_config.github":       "auth/github/config",
+		"auth_group.admins":        "auth/ldap-corp/groups/admins",
+	}, paths, "Every path should be beneath the configured mount path")
+}
+
+func TestAuthPaths_Conflict(t *testing.T) {
+	tests := []struct {
+		name    string
+		corp    string
+		partner string
+		err     bool
+	}{
+		{name: "different paths", corp: "ldap-corp", partner: "ldap-partner"},
+		{name: "default path", corp: "ldap-corp", partner: ""},
+		{name: "same path", corp: "ldap-corp", partner: "ldap-corp", err: true},
+		{name: "same path as the default", corp: "ldap/", partner: "", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf, err := ParseConfig([]byte(authPathsConfig))
+			assert.NoError(t, err, "Parsing config should return no error: %v", err)
+			conf.Auth.Ldap[0].Path = tt.corp
+			conf.Auth.Ldap[1].Path = tt.partner
+			_, err = conf.AllResources()
+			if tt.err {
+				assert.Error(t, err, "Backends mounted at the same path should return an error")
+			} else {
+				assert.NoError(t, err, "Backends mounted at different paths should return no error: %v", err)
+			}
+		})
+	}
+}
+
+func TestAuthPaths_Apply(t *testing.T) {
+	f, c := newFakeVault(t)
+	defer f.Close()
+	conf, err := ParseConfig([]byte(authPathsConfig))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	applyAndPlan(t, c, conf)
+
+	for path, url := range map[string]string{"ldap-corp": "ldaps://corp.example.com", "ldap-partner": "ldaps://partner.example.com"} {
+		assert.Equal(t, "ldap", f.get("sys/auth/" + path)["type"], "Each backend should be enabled at its own path: %s", path)
+		assert.Equal(t, url, f.get(fmt.Sprintf("auth/%s/config", path))["url"], "Each backend should be configured at its own path: %s", path)
+	}
+	assert.Equal(t, "github", f.get("sys/auth/github")["type"], "Unnamed backends should be enabled at their type")
+	assert.Equal(t, "admin", f.get("auth/ldap-corp/groups/admins")["policies"], "Groups should be written beneath the named backend")
+	assert.Nil(t, f.get("sys/auth/ldap"), "Named backends should not be enabled at their type")
+}
+
+const authTuneConfig = `
+auth {
+  github {
+    description = "GitHub SSO"
+    mountconfig {
+      default_lease_ttl = "1h"
+      listing_visibility = "unauth"
+      audit_non_hmac_request_keys = ["organization"]
+      passthrough_request_headers = ["X-Request-Id"]
+      token_type = "batch"
+    }
+  }
+}
+`
+
+func TestAuthTune(t *testing.T) {
+	f, c := newFakeVault(t)
+	defer f.Close()
+	conf, err := ParseConfig([]byte(authTuneConfig))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	assert.Empty(t, validationMessages(Validate([]ConfigFile{{Name: "auth.vc", Data: []byte(authTuneConfig)}})),
+		"Tuning options should be valid")
+	applyAndPlan(t, c, conf)
+
+	tune := func() map[string]interface{} {
+		s, err := c.Logical().Read("sys/auth/github/tune")
+		assert.NoError(t, err, "Reading tuning options should not return an error: %v", err)
+		return s.Data
+	}
+	got := tune()
+	assert.Equal(t, "unauth", got["listing_visibility"], "Listing visibility should be tuned")
+	assert.Equal(t, []interface{}{"organization"}, got["audit_non_hmac_request_keys"], "Audit keys should be tuned")
+	assert.Equal(t, []interface{}{"X-Request-Id"}, got["passthrough_request_headers"], "Headers should be tuned")
+	assert.Equal(t, "batch", got["token_type"], "Token type should be tuned")
+
+	// options and descriptions changed in Vault are planned and tuned again
+	_, err = c.Logical().Write("sys/auth/github/tune", map[string]interface{}{"description": "GitHub", "listing_visibility": "hidden"})
+	assert.NoError(t, err, "Tuning should not return an error: %v", err)
+	p, err := c.Plan(conf)
+	assert.NoError(t, err, "Plan should not return an error: %v", err)
+	var changed []string
+	for _, ch := range p.Changes {
+		for _, field := range ch.Fields {
+			changed = append(changed, fmt.Sprintf("%s.%s %s", ch.Resource, ch.Name, field.Name))
+		}
+	}
+	assert.ElementsMatch(t, []string{"auth.github description", "auth.github listing_visibility"}, changed,
+		"Only the changed options should be planned")
+	applyAndPlan(t, c, conf)
+	got = tune()
+	assert.Equal(t, "GitHub SSO", got["description"], "The description should be tuned again")
+	assert.Equal(t, "unauth", got["listing_visibility"], "Listing visibility should be tuned again")
+}
+
+func (vsc *vaultServerConfigTestSuite) TestVCClient_AuthPaths() {
+	conf, err := ParseConfig([]byte(`
+auth {
+  ldap "corp" {
+    path = "ldap-corp"
+    authconfig {
+      url = "ldaps://corp.example.com"
+    }
+    group "admins" {
+      options {
+        policies = "admin"
+      }
+    }
+  }
+  ldap "partner" {
+    path = "ldap-partner"
+    authconfig {
+      url = "ldaps://partner.example.com"
+    }
+  }
+}`))
+	assert.NoError(vsc.T(), err, "Parsing config should not return an error: %v", err)
+	_, err = vsc.vtc.Apply(context.Background(), conf)
+	assert.NoError(vsc.T(), err, "Apply should not return an error: %v", err)
+
+	for path, url := range map[string]string{"ldap-corp": "ldaps://corp.example.com", "ldap-partner": "ldaps://partner.example.com"} {
+		s, err := vsc.vtc.Logical().Read(fmt.Sprintf("auth/%s/config", path))
+		assert.NoError(vsc.T(), err, "Reading LDAP config should not return an error: %v", err)
+		assert.Equal(vsc.T(), url, s.Data["url"], "Each backend should be configured at its own path: %s", path)
+	}
+	s, err := vsc.vtc.Logical().Read("auth/ldap-corp/groups/admins")
+	assert.NoError(vsc.T(), err, "Reading LDAP group should not return an error: %v", err)
+	assert.NotNil(vsc.T(), s, "Groups should be written beneath the named backend")
+
+	applyAndPlan(vsc.T(), vsc.vtc, conf, "auth", "auth_config", "auth_group")
+}
+
+func (vsc *vaultServerConfigTestSuite) TestVCClient_AuthTune() {
+	conf, err := ParseConfig([]byte(`
+auth {
+  github "sso" {
+    path = "github-sso"
+    description = "GitHub SSO"
+    mountconfig {
+      default_lease_ttl = "1h"
+      listing_visibility = "unauth"
+      audit_non_hmac_request_keys = ["organization"]
+      passthrough_request_headers = ["X-Request-Id"]
+      token_type = "batch"
+    }
+  }
+}`))
+	assert.NoError(vsc.T(), err, "Parsing config should not return an error: %v", err)
+	_, err = vsc.vtc.Apply(context.Background(), conf)
+	assert.NoError(vsc.T(), err, "Apply should not return an error: %v", err)
+
+	s, err := vsc.vtc.Logical().Read("sys/auth/github-sso/tune")
+	assert.NoError(vsc.T(), err, "Reading tuning options should not return an error: %v", err)
+	assert.Equal(vsc.T(), "unauth", s.Data["listing_visibility"], "Listing visibility should be tuned")
+	assert.Equal(vsc.T(), "batch", s.Data["token_type"], "Token type should be tuned")
+
+	applyAndPlan(vsc.T(), vsc.vtc, conf, "auth")
+}
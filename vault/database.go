@@ -0,0 +1,389 @@
+package vault
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+func init() {
+	Register(ResourceType{Name: "database_connection", New: func() Block { return &DatabaseConnection{} }, List: listDatabaseConnections})
+	Register(ResourceType{Name: "database_role", New: func() Block { return &DatabaseRole{} }, List: listDatabaseRoles})
+}
+
+// DatabaseConnection is a connection of the database secrets engine mounted
+// at Mount, which defaults to database. Username and Password may be
+// encrypted, the password is not returned by Vault so is only written.
+// Options are written along with the other fields, e.g. max_open_connections
+type DatabaseConnection struct {
+	Name             string                 `hcl:",key"`
+	Mount            string                 `hcl:"mount"`
+	PluginName       string                 `hcl:"plugin_name" validate:"required" mapstructure:"plugin_name"`
+	ConnectionURL    string                 `hcl:"connection_url" mapstructure:"connection_url"`
+	Username         string                 `hcl:"username" mapstructure:"username"`
+	Password         string                 `hcl:"password" mapstructure:"password"`
+	AllowedRoles     []string               `hcl:"allowed_roles" mapstructure:"allowed_roles"`
+	VerifyConnection *bool                  `hcl:"verify_connection"`
+	Options          map[string]interface{} `hcl:"options"`
+	Depends          []string               `hcl:"depends_on"`
+}
+
+// DatabaseRole is a role of the database secrets engine, roles with a
+// Username are static roles whose password is rotated every RotationPeriod,
+// other roles create credentials with the creation statements. Statements
+// can be read from files, Options are written along with the other fields,
+// e.g. renew_statements
+type DatabaseRole struct {
+	Name                     string                 `hcl:",key"`
+	Mount                    string                 `hcl:"mount"`
+	DBName                   string                 `hcl:"db_name" validate:"required" mapstructure:"db_name"`
+	CreationStatements       []string               `hcl:"creation_statements" mapstructure:"creation_statements"`
+	CreationStatementsFile   string                 `hcl:"creation_statements_file"`
+	RevocationStatements     []string               `hcl:"revocation_statements" mapstructure:"revocation_statements"`
+	RevocationStatementsFile string                 `hcl:"revocation_statements_file"`
+	DefaultTTL               string                 `hcl:"default_ttl" mapstructure:"default_ttl"`
+	MaxTTL                   string                 `hcl:"max_ttl" mapstructure:"max_ttl"`
+	Username                 string                 `hcl:"username" mapstructure:"username"`
+	RotationPeriod           string                 `hcl:"rotation_period" mapstructure:"rotation_period"`
+	RotationStatements       []string               `hcl:"rotation_statements" mapstructure:"rotation_statements"`
+	RotationStatementsFile   string                 `hcl:"rotation_statements_file"`
+	Options                  map[string]interface{} `hcl:"options"`
+	Depends                  []string               `hcl:"depends_on"`
+}
+
+// databaseMount returns the path of a database mount, which defaults to database
+func databaseMount(mount string) string {
+	if m := strings.Trim(mount, "/"); m != "" {
+		return m
+	}
+
+	return "database"
+}
+
+// databaseData returns the fields of a connection or role to write to
+// Vault along with its options
+func databaseData(v interface{}, options map[string]interface{}) map[string]interface{} {
+	data := flattenOptions(options)
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	for k, v := range ConvertMapStringInterface(v) {
+		data[k] = v
+	}
+
+	return data
+}
+
+func (d *DatabaseConnection) Kind() string { return "database_connection" }
+func (d *DatabaseConnection) ID() string   { return d.Name }
+
+func (d *DatabaseConnection) VaultPath() string {
+	return fmt.Sprintf("%s/config/%s", databaseMount(d.Mount), d.Name)
+}
+
+func (d *DatabaseConnection) DependsOn() []string { return d.Depends }
+
+func (d *DatabaseConnection) encryptedValues() []*string {
+	return []*string{&d.Username, &d.Password}
+}
+
+// Read returns the connection with its connection details, such as the
+// connection URL, merged in
+func (d *DatabaseConnection) Read(c *VCClient) (map[string]interface{}, bool, error) {
+	exists, err := c.MountExist(databaseMount(d.Mount))
+	if err != nil || !exists {
+		return nil, false, err
+	}
+	state, exists, err := c.readData(d.VaultPath())
+	if err != nil || !exists {
+		return nil, false, err
+	}
+	if details, ok := state["connection_details"].(map[string]interface{}); ok {
+		for k, v := range details {
+			state[k] = v
+		}
+	}
+
+	return state, true, nil
+}
+
+// Diff compares the connection with Vault, the password is not returned by
+// Vault so is not compared
+func (d *DatabaseConnection) Diff(state map[string]interface{}, exists bool) Change {
+	desired := databaseData(d, d.Options)
+	delete(desired, "password")
+	ch := diffResource(d.Kind(), d.ID(), d.VaultPath(), desired, state, exists)
+	ch.Sensitive = true
+
+	return ch
+}
+
+func (d *DatabaseConnection) Create(c *VCClient) error {
+	return d.Update(c)
+}
+
+func (d *DatabaseConnection) Update(c *VCClient) error {
+	data := databaseData(d, d.Options)
+	if d.VerifyConnection != nil {
+		data["verify_connection"] = *d.VerifyConnection
+	}
+	if _, err := c.Logical().Write(d.VaultPath(), data); err != nil {
+		return fmt.Errorf("Error writing database connection: %s\nError: %v", d.Name, err)
+	}
+
+	return nil
+}
+
+func (d *DatabaseConnection) Delete(c *VCClient) error {
+	if _, err := c.Logical().Delete(d.VaultPath()); err != nil {
+		return fmt.Errorf("Error deleting database connection: %s\nError: %v", d.Name, err)
+	}
+
+	return nil
+}
+
+func (r *DatabaseRole) Kind() string { return "database_role" }
+func (r *DatabaseRole) ID() string   { return r.Name }
+
+// VaultPath returns the path of the role, static roles are stored apart
+// from dynamic roles
+func (r *DatabaseRole) VaultPath() string {
+	if r.static() {
+		return fmt.Sprintf("%s/static-roles/%s", databaseMount(r.Mount), r.Name)
+	}
+
+	return fmt.Sprintf("%s/roles/%s", databaseMount(r.Mount), r.Name)
+}
+
+func (r *DatabaseRole) DependsOn() []string { return r.Depends }
+
+func (r *DatabaseRole) references() []string {
+	return []string{fmt.Sprintf("database_connection.%s", r.DBName)}
+}
+
+func (r *DatabaseRole) static() bool {
+	return r.Username != ""
+}
+
+// loadFiles reads the statement files into the statements of the role,
+// each file is a single statement which the database plugin splits on
+// semicolons
+func (r *DatabaseRole) loadFiles() error {
+	files := []struct {
+		file       string
+		statements *[]string
+	}{
+		{r.CreationStatementsFile, &r.CreationStatements},
+		{r.RevocationStatementsFile, &r.RevocationStatements},
+		{r.RotationStatementsFile, &r.RotationStatements},
+	}
+	for _, f := range files {
+		if f.file == "" {
+			continue
+		}
+		b, err := ioutil.ReadFile(f.file)
+		if err != nil {
+			return fmt.Errorf("Error reading statements file for database role: %s\nError: %v", r.Name, err)
+		}
+		*f.statements = append(*f.statements, strings.TrimSpace(string(b)))
+	}
+
+	return nil
+}
+
+func (r *DatabaseRole) Read(c *VCClient) (map[string]interface{}, bool, error) {
+	exists, err := c.MountExist(databaseMount(r.Mount))
+	if err != nil || !exists {
+		return nil, false, err
+	}
+
+	return c.readData(r.VaultPath())
+}
+
+func (r *DatabaseRole) Diff(state map[string]interface{}, exists bool) Change {
+	return diffValues(r.Kind(), r.ID(), r.VaultPath(), databaseData(r, r.Options), state, exists, statementsEqual)
+}
+
+// statementsEqual compares lists in order as statements are run in the
+// order they are listed, other values are compared as config fields
+func statementsEqual(desired, current interface{}) bool {
+	d, ok := desired.([]string)
+	if !ok {
+		return valuesEqual(desired, current)
+	}
+	c, ok := current.([]interface{})
+	if !ok || len(d) != len(c) {
+		return false
+	}
+	for i := range d {
+		if d[i] != fmt.Sprint(c[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r *DatabaseRole) Create(c *VCClient) error {
+	return r.Update(c)
+}
+
+func (r *DatabaseRole) Update(c *VCClient) error {
+	if _, err := c.Logical().Write(r.VaultPath(), databaseData(r, r.Options)); err != nil {
+		return fmt.Errorf("Error writing database role: %s\nError: %v", r.Name, err)
+	}
+
+	return nil
+}
+
+func (r *DatabaseRole) Delete(c *VCClient) error {
+	if _, err := c.Logical().Delete(r.VaultPath()); err != nil {
+		return fmt.Errorf("Error deleting database role: %s\nError: %v", r.Name, err)
+	}
+
+	return nil
+}
+
+// databaseMounts returns the database mounts declared in the configuration,
+// either as a mount or by a connection or role, that exist on the server
+func (c *VCClient) databaseMounts(conf Config) ([]string, error) {
+	var paths []string
+	for _, m := range conf.Mounts {
+		if m.Config.PathType == "database" {
+			paths = append(paths, strings.Trim(m.Path, "/"))
+		}
+	}
+	for _, r := range conf.Resources {
+		switch t := r.(type) {
+		case *DatabaseConnection:
+			paths = append(paths, databaseMount(t.Mount))
+		case *DatabaseRole:
+			paths = append(paths, databaseMount(t.Mount))
+		}
+	}
+
+	var out []string
+	for _, p := range paths {
+		if contains(out, p) {
+			continue
+		}
+		exists, err := c.MountExist(p)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			out = append(out, p)
+		}
+	}
+
+	return out, nil
+}
+
+// listDatabaseConnections returns every connection in the database mounts
+// of the configuration, the password is not returned by Vault
+func listDatabaseConnections(c *VCClient, conf Config) ([]Resource, error) {
+	mounts, err := c.databaseMounts(conf)
+	if err != nil {
+		return nil, err
+	}
+	var out []Resource
+	for _, m := range mounts {
+		keys, err := c.listKeys(fmt.Sprintf("%s/config", m))
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range keys {
+			d := &DatabaseConnection{Name: k, Mount: m}
+			state, exists, err := d.Read(c)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				continue
+			}
+			d.PluginName, _ = state["plugin_name"].(string)
+			d.ConnectionURL, _ = state["connection_url"].(string)
+			d.Username, _ = state["username"].(string)
+			d.AllowedRoles, _ = toStringSlice(state["allowed_roles"])
+			if details, ok := state["connection_details"].(map[string]interface{}); ok {
+				if options := cleanOptions(details, "connection_url", "username"); len(options) > 0 {
+					d.Options = options
+				}
+			}
+			out = append(out, d)
+		}
+	}
+
+	return out, nil
+}
+
+// listDatabaseRoles returns every dynamic and static role in the database
+// mounts of the configuration
+func listDatabaseRoles(c *VCClient, conf Config) ([]Resource, error) {
+	mounts, err := c.databaseMounts(conf)
+	if err != nil {
+		return nil, err
+	}
+	var out []Resource
+	for _, m := range mounts {
+		for _, kind := range []string{"roles", "static-roles"} {
+			keys, err := c.listKeys(fmt.Sprintf("%s/%s", m, kind))
+			if err != nil {
+				return nil, err
+			}
+			for _, k := range keys {
+				data, exists, err := c.readData(fmt.Sprintf("%s/%s/%s", m, kind, k))
+				if err != nil {
+					return nil, err
+				}
+				if exists {
+					out = append(out, importDatabaseRole(k, m, data))
+				}
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// importDatabaseRole converts a role read from Vault into a DatabaseRole,
+// values that do not have a field are kept in the options
+func importDatabaseRole(name, mount string, data map[string]interface{}) *DatabaseRole {
+	r := &DatabaseRole{Name: name, Mount: mount}
+	r.DBName, _ = data["db_name"].(string)
+	r.Username, _ = data["username"].(string)
+	r.CreationStatements = importStatements(data["creation_statements"])
+	r.RevocationStatements = importStatements(data["revocation_statements"])
+	r.RotationStatements = importStatements(data["rotation_statements"])
+	for field, key := range map[*string]string{&r.DefaultTTL: "default_ttl", &r.MaxTTL: "max_ttl", &r.RotationPeriod: "rotation_period"} {
+		if v, ok := ttlSeconds(data[key]); ok {
+			*field = formatTTL(int(v))
+		}
+	}
+	// ttl and last_vault_rotation are the state of the password of static roles
+	options := cleanOptions(data, "db_name", "username", "creation_statements", "revocation_statements",
+		"rotation_statements", "default_ttl", "max_ttl", "rotation_period", "ttl", "last_vault_rotation")
+	if len(options) > 0 {
+		r.Options = options
+	}
+
+	return r
+}
+
+// importStatements returns the statements read from Vault, unlike other
+// lists a string is a single statement as statements may contain commas
+func importStatements(v interface{}) []string {
+	var out []string
+	switch t := v.(type) {
+	case []interface{}:
+		for _, e := range t {
+			out = append(out, fmt.Sprint(e))
+		}
+	case string:
+		if t != "" {
+			out = append(out, t)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,197 @@
+package vault
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// DriftStatus describes how a resource on the Vault server differs from
+// the configuration
+type DriftStatus string
+
+const (
+	DriftNone      DriftStatus = "in-sync"
+	DriftMissing   DriftStatus = "missing"
+	DriftDiffers   DriftStatus = "differs"
+	DriftUnmanaged DriftStatus = "unmanaged"
+)
+
+// DriftField is a field whose value on the Vault server differs from the
+// configuration, sensitive values are masked
+type DriftField struct {
+	Name    string `json:"name"`
+	Current string `json:"current"`
+	Desired string `json:"desired"`
+}
+
+// DriftItem is the drift status of a single resource
+type DriftItem struct {
+	Resource string       `json:"resource"`
+	Name     string       `json:"name"`
+	Path     string       `json:"path"`
+	Status   DriftStatus  `json:"status"`
+	Fields   []DriftField `json:"fields,omitempty"`
+}
+
+// DriftReport lists every resource that is declared in the configuration
+// or exists on the Vault server along with its drift status
+type DriftReport struct {
+	Resources []DriftItem `json:"resources"`
+}
+
+// Drift compares the configuration with the Vault server, reporting
+// resources that are missing, differ or exist without being declared
+func (c *VCClient) Drift(conf Config) (*DriftReport, error) {
+	p, err := c.Plan(conf)
+	if err != nil {
+		return nil, err
+	}
+	unmanaged, err := c.PlanPrune(conf, PruneTypes())
+	if err != nil {
+		return nil, err
+	}
+	p.Changes = append(p.Changes, unmanaged...)
+
+	statuses := map[ChangeAction]DriftStatus{
+		ActionNoop:   DriftNone,
+		ActionCreate: DriftMissing,
+		ActionUpdate: DriftDiffers,
+		ActionDelete: DriftUnmanaged,
+	}
+	r := &DriftReport{}
+	for _, ch := range p.Changes {
+		item := DriftItem{
+			Resource: ch.Resource,
+			Name:     ch.Name,
+			Path:     ch.Path,
+			Status:   statuses[ch.Action],
+		}
+		if ch.Action == ActionUpdate {
+			for _, f := range ch.Fields {
+				sensitive := ch.Sensitive || sensitiveKey.MatchString(f.Name)
+				item.Fields = append(item.Fields, DriftField{
+					Name:    f.Name,
+					Current: formatValue(f.Current, sensitive),
+					Desired: formatValue(f.Desired, sensitive),
+				})
+			}
+		}
+		r.Resources = append(r.Resources, item)
+	}
+
+	return r, nil
+}
+
+// HasDrift returns true if any resource is not in sync with the configuration
+func (r *DriftReport) HasDrift() bool {
+	for _, v := range r.Resources {
+		if v.Status != DriftNone {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (i DriftItem) message() string {
+	switch i.Status {
+	case DriftMissing:
+		return fmt.Sprintf("%s.%s is declared but missing from Vault", i.Resource, i.Name)
+	case DriftUnmanaged:
+		return fmt.Sprintf("%s.%s exists in Vault but is not declared", i.Resource, i.Name)
+	}
+	msg := fmt.Sprintf("%s.%s differs from configuration", i.Resource, i.Name)
+	for _, f := range i.Fields {
+		msg = fmt.Sprintf("%s\n%s: %s => %s", msg, f.Name, f.Current, f.Desired)
+	}
+
+	return msg
+}
+
+// WriteText writes the resources that have drifted in a human readable format
+func (r *DriftReport) WriteText(w io.Writer) error {
+	drifted := 0
+	for _, v := range r.Resources {
+		if v.Status == DriftNone {
+			continue
+		}
+		drifted++
+		if _, err := fmt.Fprintf(w, "%-9s %s.%s (%s)\n", v.Status, v.Resource, v.Name, v.Path); err != nil {
+			return err
+		}
+		for _, f := range v.Fields {
+			if _, err := fmt.Fprintf(w, "          %s: %s => %s\n", f.Name, f.Current, f.Desired); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintf(w, "\n%d of %d resources have drifted.\n", drifted, len(r.Resources))
+
+	return err
+}
+
+// WriteJSON writes the report as JSON
+func (r *DriftReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(r)
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitTestSuite struct {
+	XMLName   xml.Name        `xml:"testsuite"`
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+// WriteJUnit writes the report as JUnit XML, with a test case for each
+// resource that fails if the resource has drifted
+func (r *DriftReport) WriteJUnit(w io.Writer) error {
+	ts := junitTestSuite{
+		Name:  "vault-config drift",
+		Tests: len(r.Resources),
+	}
+	for _, v := range r.Resources {
+		tc := junitTestCase{
+			Name:      fmt.Sprintf("%s.%s", v.Resource, v.Name),
+			ClassName: v.Resource,
+		}
+		if v.Status != DriftNone {
+			ts.Failures++
+			tc.Failure = &junitFailure{
+				Message: fmt.Sprintf("%s is %s", v.Path, v.Status),
+				Type:    string(v.Status),
+				Body:    v.message(),
+			}
+		}
+		ts.TestCases = append(ts.TestCases, tc)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(ts); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+
+	return err
+}
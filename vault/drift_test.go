@@ -0,0 +1,50 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var driftReport = DriftReport{
+	Resources: []DriftItem{
+		{Resource: "mount", Name: "app1", Path: "example/app1", Status: DriftNone},
+		{Resource: "policy", Name: "example-policy-1", Path: "sys/policy/example-policy-1", Status: DriftDiffers,
+			Fields: []DriftField{{Name: "rules", Current: `"a"`, Desired: `"b"`}}},
+		{Resource: "policy", Name: "old-policy", Path: "sys/policy/old-policy", Status: DriftUnmanaged},
+	},
+}
+
+func TestDriftReport_HasDrift(t *testing.T) {
+	assert.True(t, driftReport.HasDrift(), "Report with drifted resources should have drift")
+	inSync := DriftReport{Resources: driftReport.Resources[:1]}
+	assert.False(t, inSync.HasDrift(), "Report with only in-sync resources should not have drift")
+}
+
+func TestDriftReport_WriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	err := driftReport.WriteJSON(&buf)
+	assert.NoError(t, err, "Writing JSON should return no error: %v", err)
+
+	var r DriftReport
+	err = json.Unmarshal(buf.Bytes(), &r)
+	assert.NoError(t, err, "JSON report should be valid: %v", err)
+	assert.Equal(t, driftReport, r, "JSON report should match report")
+}
+
+func TestDriftReport_WriteJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	err := driftReport.WriteJUnit(&buf)
+	assert.NoError(t, err, "Writing JUnit should return no error: %v", err)
+
+	var ts junitTestSuite
+	err = xml.Unmarshal(buf.Bytes(), &ts)
+	assert.NoError(t, err, "JUnit report should be valid XML: %v", err)
+	assert.Equal(t, 3, ts.Tests, "Each resource should be a test case")
+	assert.Equal(t, 2, ts.Failures, "Each drifted resource should be a failure")
+	assert.Nil(t, ts.TestCases[0].Failure, "In-sync resource should not fail")
+	assert.Contains(t, ts.TestCases[1].Failure.Body, "rules", "Failure should list fields that differ")
+}
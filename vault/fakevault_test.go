@@ -0,0 +1,275 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// fakeVault is an in-memory Vault server for unit tests. Data written to a
+// path is returned when the path is read and listed, mounts, auth backends
+// and audit devices are returned by sys/mounts, sys/auth and sys/audit.
+// Handlers replace the store for endpoints that generate values
+type fakeVault struct {
+	srv      *httptest.Server
+	mu       sync.Mutex
+	data     map[string]map[string]interface{}
+	handlers map[string]func(method string, body map[string]interface{}) map[string]interface{}
+	prefixes map[string]func(method, path string, body map[string]interface{}) map[string]interface{}
+	// writes lists every write and delete in the order they were made,
+	// e.g. "PUT auth/approle/role/app1"
+	writes []string
+}
+
+// newFakeVault starts a fake Vault server and returns a client for it, the
+// server should be closed with Close
+func newFakeVault(t *testing.T) (*fakeVault, *VCClient) {
+	f := &fakeVault{
+		data:     make(map[string]map[string]interface{}),
+		handlers: make(map[string]func(string, map[string]interface{}) map[string]interface{}),
+		prefixes: make(map[string]func(string, string, map[string]interface{}) map[string]interface{}),
+	}
+	// the backends and policies every Vault server has
+	for path, data := range map[string]map[string]interface{}{
+		"sys/mounts/sys":           {"type": "system"},
+		"sys/mounts/cubbyhole":     {"type": "cubbyhole"},
+		"sys/mounts/identity":      {"type": "identity"},
+		"sys/auth/token":           {"type": "token"},
+		"sys/policies/acl/root":    {"name": "root", "policy": ""},
+		"sys/policies/acl/default": {"name": "default", "policy": ""},
+	} {
+		f.serve(http.MethodPut, path, data)
+	}
+	f.srv = httptest.NewServer(f)
+
+	client, err := api.NewClient(&api.Config{Address: f.srv.URL})
+	if err != nil {
+		t.Fatalf("Error creating Vault client: %v", err)
+	}
+	client.SetToken("root")
+
+	return f, &VCClient{Client: client, Parallelism: DefaultParallelism, EncryptionKey: []byte("abcdefghijklmnopqrstuvwxyz012345")}
+}
+
+func (f *fakeVault) Close() {
+	f.srv.Close()
+}
+
+// handle replaces the store for a path, the data returned is sent to the client
+func (f *fakeVault) handle(path string, h func(method string, body map[string]interface{}) map[string]interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.handlers[path] = h
+}
+
+// handlePrefix replaces the store for every path beneath a prefix, the
+// handler is called with the path that was requested
+func (f *fakeVault) handlePrefix(prefix string, h func(method, path string, body map[string]interface{}) map[string]interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.prefixes[prefix] = h
+}
+
+// get returns the data stored at a path
+func (f *fakeVault) get(path string) map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.data[path]
+}
+
+// resetWrites clears the writes recorded so far
+func (f *fakeVault) resetWrites() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.writes = nil
+}
+
+func (f *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/"), "/")
+	// auth backends can be tuned through sys/mounts as well as sys/auth
+	if strings.HasPrefix(path, "sys/mounts/auth/") {
+		path = "sys/" + strings.TrimPrefix(path, "sys/mounts/")
+	}
+	method := r.Method
+	if method == http.MethodGet && r.URL.Query().Get("list") == "true" {
+		method = "LIST"
+	}
+	var body map[string]interface{}
+	if r.Body != nil {
+		d := json.NewDecoder(r.Body)
+		d.UseNumber()
+		d.Decode(&body)
+	}
+	if method != http.MethodGet && method != "LIST" {
+		f.writes = append(f.writes, fmt.Sprintf("%s %s", method, path))
+	}
+
+	var data map[string]interface{}
+	if h, ok := f.handlers[path]; ok {
+		data = h(method, body)
+	} else if h := f.prefixHandler(path); h != nil {
+		data = h(method, path, body)
+	} else {
+		data = f.serve(method, path, body)
+	}
+	// writes that return nothing have no content, reads of missing paths
+	// are not found
+	if data == nil && method != http.MethodGet && method != "LIST" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if data == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"errors":[]}`))
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+}
+
+// prefixHandler returns the handler for the prefix of a path, or nil
+func (f *fakeVault) prefixHandler(path string) func(string, string, map[string]interface{}) map[string]interface{} {
+	for prefix, h := range f.prefixes {
+		if strings.HasPrefix(path, prefix+"/") {
+			return h
+		}
+	}
+
+	return nil
+}
+
+// serve handles a request with the store, nil is returned when a path
+// that is read does not exist
+func (f *fakeVault) serve(method, path string, body map[string]interface{}) map[string]interface{} {
+	switch {
+	case method == "LIST":
+		return f.list(path)
+	case method == http.MethodDelete:
+		delete(f.data, path)
+		return map[string]interface{}{}
+	case path == "sys/mounts" || path == "sys/auth" || path == "sys/audit":
+		return f.mounts(path)
+	case strings.HasSuffix(path, "/tune"):
+		return f.tune(method, strings.TrimSuffix(path, "/tune"), body)
+	case method != http.MethodGet:
+		if body == nil {
+			body = make(map[string]interface{})
+		}
+		if strings.HasPrefix(path, "sys/mounts/") || strings.HasPrefix(path, "sys/auth/") {
+			body["accessor"] = fmt.Sprintf("%s_%x", body["type"], len(f.data))
+			config, _ := body["config"].(map[string]interface{})
+			config = secondsTTLs(config)
+			for _, k := range []string{"default_lease_ttl", "max_lease_ttl"} {
+				if _, ok := config[k]; !ok {
+					config[k] = 0
+				}
+			}
+			body["config"] = config
+		}
+		f.data[path] = body
+		return map[string]interface{}{}
+	}
+
+	return f.data[path]
+}
+
+// list returns the keys beneath a path, keys with children end in a slash
+func (f *fakeVault) list(path string) map[string]interface{} {
+	seen := make(map[string]bool)
+	var keys []interface{}
+	for p := range f.data {
+		if !strings.HasPrefix(p, path+"/") {
+			continue
+		}
+		k := strings.TrimPrefix(p, path+"/")
+		if i := strings.Index(k, "/"); i >= 0 {
+			k = k[:i+1]
+		}
+		if !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].(string) < keys[j].(string) })
+
+	return map[string]interface{}{"keys": keys}
+}
+
+// mounts returns the mounts, auth backends or audit devices keyed by
+// their path, as sys/mounts, sys/auth and sys/audit do
+func (f *fakeVault) mounts(prefix string) map[string]interface{} {
+	out := make(map[string]interface{})
+	for p, d := range f.data {
+		if strings.HasPrefix(p, prefix+"/") && !strings.HasSuffix(p, "/tune") {
+			path := strings.TrimPrefix(p, prefix+"/")
+			m := make(map[string]interface{}, len(d)+1)
+			for k, v := range d {
+				m[k] = v
+			}
+			m["path"] = path + "/"
+			out[path+"/"] = m
+		}
+	}
+
+	return out
+}
+
+// tune reads or tunes the config of a mount or auth backend, TTLs are
+// stored as seconds as Vault does
+func (f *fakeVault) tune(method, path string, body map[string]interface{}) map[string]interface{} {
+	m, ok := f.data[path]
+	if !ok {
+		return nil
+	}
+	config, _ := m["config"].(map[string]interface{})
+	if method == http.MethodGet {
+		out := make(map[string]interface{}, len(config)+1)
+		for k, v := range config {
+			out[k] = v
+		}
+		out["description"] = m["description"]
+		return out
+	}
+	for k, v := range secondsTTLs(body) {
+		switch k {
+		case "description":
+			m["description"] = v
+		case "options":
+			m["options"] = v
+		default:
+			config[k] = v
+		}
+	}
+
+	return map[string]interface{}{}
+}
+
+// secondsTTLs converts the TTLs of a mount's config into seconds
+func secondsTTLs(in map[string]interface{}) map[string]interface{} {
+	config := make(map[string]interface{}, len(in))
+	for k, v := range in {
+		if s, ok := v.(string); ok && strings.HasSuffix(k, "_ttl") {
+			v = 0
+			if d, err := time.ParseDuration(s); err == nil {
+				v = int(d.Seconds())
+			}
+		}
+		config[k] = v
+	}
+
+	return config
+}
@@ -0,0 +1,197 @@
+package vault
+
+import (
+	"fmt"
+	"strings"
+)
+
+// policyKeys are the options that contain the names of policies, resources
+// that set them are applied after the policies they reference
+var policyKeys = []string{"policies", "policy", "token_policies", "allowed_policies"}
+
+// Dependent is implemented by resources with explicit dependencies, they are
+// references to other resources in the form <type>.<name>, e.g. policy.admin
+type Dependent interface {
+	DependsOn() []string
+}
+
+// policyReferrer is implemented by resources that can reference policies
+type policyReferrer interface {
+	policies() []string
+}
+
+// referrer is implemented by resources that reference other resources by
+// name, the referenced resources may already exist in Vault so only those
+// in the configuration are depended on
+type referrer interface {
+	references() []string
+}
+
+// containers are the types of resource that other resources are stored
+// beneath, a resource depends on the container its path is under
+var containers = []string{"mount", "auth"}
+
+// policyNames returns the policy names referenced in options
+func policyNames(options map[string]interface{}) []string {
+	var out []string
+	for _, k := range policyKeys {
+		if v, ok := options[k]; ok {
+			if names, ok := toStringSlice(v); ok {
+				out = append(out, names...)
+			}
+		}
+	}
+
+	return out
+}
+
+func resourceRef(r Resource) string {
+	return fmt.Sprintf("%s.%s", r.Kind(), r.ID())
+}
+
+// dependencies returns the indexes of the resources each resource depends on
+func dependencies(resources []Resource) ([][]int, error) {
+	refs := make(map[string][]int)
+	for i, r := range resources {
+		refs[resourceRef(r)] = append(refs[resourceRef(r)], i)
+	}
+
+	deps := make([][]int, len(resources))
+	for i, r := range resources {
+		// the closest container the resource is stored beneath
+		parent, length := -1, 0
+		for j, o := range resources {
+			if i == j || !contains(containers, o.Kind()) || o.Kind() == r.Kind() {
+				continue
+			}
+			p := o.VaultPath()
+			if strings.HasPrefix(r.VaultPath(), p+"/") && len(p) > length {
+				parent, length = j, len(p)
+			}
+		}
+		if parent >= 0 {
+			deps[i] = append(deps[i], parent)
+		}
+
+		if pr, ok := r.(policyReferrer); ok {
+			for _, p := range pr.policies() {
+				deps[i] = append(deps[i], refs[fmt.Sprintf("policy.%s", p)]...)
+			}
+		}
+
+		if rr, ok := r.(referrer); ok {
+			for _, ref := range rr.references() {
+				deps[i] = append(deps[i], refs[ref]...)
+			}
+		}
+
+		if d, ok := r.(Dependent); ok {
+			for _, ref := range d.DependsOn() {
+				j, ok := refs[ref]
+				if !ok {
+					return nil, fmt.Errorf("%s depends on unknown resource: %s", resourceRef(r), ref)
+				}
+				deps[i] = append(deps[i], j...)
+			}
+		}
+	}
+
+	return deps, nil
+}
+
+// sortResources orders resources so every resource comes after the resources
+// it depends on, otherwise the original order is kept
+func sortResources(resources []Resource) ([]Resource, error) {
+	deps, err := dependencies(resources)
+	if err != nil {
+		return nil, err
+	}
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make([]int, len(resources))
+	out := make([]Resource, 0, len(resources))
+	var stack []int
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case done:
+			return nil
+		case visiting:
+			var cycle []string
+			for k := len(stack) - 1; k >= 0; k-- {
+				cycle = append([]string{resourceRef(resources[stack[k]])}, cycle...)
+				if stack[k] == i {
+					break
+				}
+			}
+			cycle = append(cycle, resourceRef(resources[i]))
+			return fmt.Errorf("Dependency cycle between resources: %s", strings.Join(cycle, " -> "))
+		}
+		state[i] = visiting
+		stack = append(stack, i)
+		for _, d := range deps[i] {
+			if d == i {
+				continue
+			}
+			if err := visit(d); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[i] = done
+		out = append(out, resources[i])
+
+		return nil
+	}
+	for i := range resources {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+
+	return out, nil
+}
+
+// checkMounts returns an error if a secret is not beneath a mount declared
+// in the configuration or already on the Vault server
+func (c *VCClient) checkMounts(resources []Resource) error {
+	var declared, existing []string
+	for _, r := range resources {
+		if r.Kind() == "mount" {
+			declared = append(declared, r.VaultPath())
+		}
+	}
+	for _, r := range resources {
+		if r.Kind() != "secret" || underPath(r.VaultPath(), declared) {
+			continue
+		}
+		if existing == nil {
+			mounts, err := c.currentMounts()
+			if err != nil {
+				return err
+			}
+			for k := range mounts {
+				existing = append(existing, strings.TrimSuffix(k, "/"))
+			}
+		}
+		if !underPath(r.VaultPath(), existing) {
+			return fmt.Errorf("No mount declared or existing for secret: %s (%s)", r.ID(), r.VaultPath())
+		}
+	}
+
+	return nil
+}
+
+// underPath returns true if path is beneath one of the parent paths
+func underPath(path string, parents []string) bool {
+	for _, p := range parents {
+		if strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,68 @@
+package vault
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func refs(resources []Resource) []string {
+	var out []string
+	for _, r := range resources {
+		out = append(out, resourceRef(r))
+	}
+
+	return out
+}
+
+func indexOf(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func TestSortResources(t *testing.T) {
+	conf, err := ParseConfig([]byte(hcl_config))
+	if err != nil {
+		t.Fatalf("Error decoding HCL: %v", err)
+	}
+	conf.Policies[0].DependsOn = []string{"token_role.example_period_token_role"}
+	conf.TokenRoles[0].Options["allowed_policies"] = "example-policy-2"
+
+	resources, err := conf.AllResources()
+	assert.NoError(t, err, "Sorting resources should return no error: %v", err)
+	order := refs(resources)
+	assert.True(t, indexOf(order, "policy.example-policy-2") < indexOf(order, "token_role.example_period_token_role"),
+		"Token role should be applied after the policies it references")
+	assert.True(t, indexOf(order, "token_role.example_period_token_role") < indexOf(order, "policy.example-policy-1"),
+		"Policy should be applied after the resources it depends on")
+	assert.True(t, indexOf(order, "policy.example-policy-1") < indexOf(order, "auth_group.groupa"),
+		"Auth group should be applied after the policies it references")
+	assert.True(t, indexOf(order, "auth.ldap") < indexOf(order, "auth_config.ldap"),
+		"Auth config should be applied after the auth backend")
+	assert.True(t, indexOf(order, "mount.app1") < indexOf(order, "secret.test"),
+		"Secrets should be applied after their mount")
+}
+
+func TestSortResources_Errors(t *testing.T) {
+	conf := Config{
+		Policies: []Policy{
+			{Name: "a", DependsOn: []string{"policy.b"}},
+			{Name: "b", DependsOn: []string{"policy.a"}},
+		},
+	}
+	_, err := conf.AllResources()
+	if assert.Error(t, err, "Dependency cycles should return an error") {
+		assert.Contains(t, err.Error(), "policy.a -> policy.b -> policy.a", "Error should describe the cycle")
+	}
+
+	conf = Config{
+		Policies: []Policy{{Name: "a", DependsOn: []string{"mount.missing"}}},
+	}
+	_, err = conf.AllResources()
+	assert.Error(t, err, "Depending on an unknown resource should return an error")
+}
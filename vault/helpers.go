@@ -1,17 +1,194 @@
 package vault
 
-import "github.com/fatih/structs"
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/fatih/structs"
+)
+
+// ConvertMapStringInterface returns the fields of a struct with a
+// mapstructure tag that are not zero, pointers are returned as the value
+// they point to so a field set to its zero value can be told from one unset
 func ConvertMapStringInterface(data interface{}) map[string]interface{} {
 	f := structs.Fields(data)
 	datamap := make(map[string]interface{})
 	for _, v := range f {
 		if v.Tag("mapstructure") != "" {
 			if !v.IsZero() {
-				datamap[v.Tag("mapstructure")] = v.Value()
+				value := v.Value()
+				if rv := reflect.ValueOf(value); rv.Kind() == reflect.Ptr {
+					value = rv.Elem().Interface()
+				}
+				datamap[v.Tag("mapstructure")] = value
 			}
 		}
 	}
 
 	return datamap
 }
+
+// valuesEqual compares a value from configuration with the value read back
+// from Vault, allowing for the type conversions the API performs, such as
+// comma separated strings returned as lists and durations returned as seconds
+func valuesEqual(desired, current interface{}) bool {
+	if desired == nil || current == nil {
+		return desired == current
+	}
+
+	if dm, ok := desired.(map[string]interface{}); ok {
+		cm, ok := current.(map[string]interface{})
+		if !ok || len(dm) != len(cm) {
+			return false
+		}
+		for k, v := range dm {
+			if !valuesEqual(v, cm[k]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch current.(type) {
+	case []interface{}, []string:
+		cl, _ := toStringSlice(current)
+		if dl, ok := toStringSlice(desired); ok {
+			return stringSlicesEqual(dl, cl)
+		}
+	}
+
+	if ds, ok := desired.(string); ok {
+		if d, err := time.ParseDuration(ds); err == nil {
+			if cs, ok := ttlSeconds(current); ok {
+				return int64(d.Seconds()) == cs
+			}
+		}
+	}
+
+	return fmt.Sprint(desired) == fmt.Sprint(current)
+}
+
+// flattenOptions converts nested blocks in options, which HCL decodes as a
+// list holding a single map, into maps so they can be written to Vault
+func flattenOptions(options map[string]interface{}) map[string]interface{} {
+	if options == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(options))
+	for k, v := range options {
+		if l := objectMaps(v); len(l) == 1 {
+			if _, ok := v.(map[string]interface{}); !ok {
+				v = flattenOptions(l[0])
+			}
+		}
+		out[k] = v
+	}
+
+	return out
+}
+
+// objectMaps returns the maps in a value decoded from a block, HCL decodes
+// blocks as a list of maps which becomes a list of interfaces once a plan
+// has been saved as JSON
+func objectMaps(v interface{}) []map[string]interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{t}
+	case []map[string]interface{}:
+		return t
+	case []interface{}:
+		var out []map[string]interface{}
+		for _, e := range t {
+			m, ok := e.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			out = append(out, m)
+		}
+		return out
+	}
+
+	return nil
+}
+
+// ttlSeconds converts a TTL returned by Vault into seconds
+func ttlSeconds(v interface{}) (int64, bool) {
+	switch t := v.(type) {
+	case int:
+		return int64(t), true
+	case int64:
+		return t, true
+	case float64:
+		return int64(t), true
+	case json.Number:
+		i, err := t.Int64()
+		return i, err == nil
+	case string:
+		if i, err := strconv.ParseInt(t, 10, 64); err == nil {
+			return i, true
+		}
+		if d, err := time.ParseDuration(t); err == nil {
+			return int64(d.Seconds()), true
+		}
+	}
+
+	return 0, false
+}
+
+// stringMap converts the values of a map to strings, for options that
+// Vault only accepts as strings such as mount options
+func stringMap(m map[string]interface{}) map[string]string {
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = fmt.Sprint(v)
+	}
+
+	return out
+}
+
+// toStringSlice returns the elements of a list, or of a comma separated
+// string, as a slice of strings
+func toStringSlice(v interface{}) ([]string, bool) {
+	var out []string
+	switch t := v.(type) {
+	case []interface{}:
+		for _, e := range t {
+			out = append(out, fmt.Sprint(e))
+		}
+	case []string:
+		out = append(out, t...)
+	case string:
+		if t == "" {
+			return []string{}, true
+		}
+		for _, e := range strings.Split(t, ",") {
+			out = append(out, strings.TrimSpace(e))
+		}
+	default:
+		return nil, false
+	}
+
+	return out, true
+}
+
+func stringSlicesEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string{}, a...)
+	b = append([]string{}, b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,536 @@
+package vault
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+func init() {
+	Register(ResourceType{Name: "identity_group", New: func() Block { return &IdentityGroup{} }, List: listIdentityGroups})
+	Register(ResourceType{Name: "identity_group_alias", New: func() Block { return &IdentityGroupAlias{} }, List: listIdentityGroupAliases})
+	Register(ResourceType{Name: "identity_entity", New: func() Block { return &IdentityEntity{} }, List: listIdentityEntities})
+}
+
+// IdentityGroup is a group in the identity secrets engine, internal groups
+// contain other groups and external groups are bound to a group of an auth
+// backend with an identity_group_alias
+type IdentityGroup struct {
+	Name             string                 `hcl:",key"`
+	Type             string                 `hcl:"type" mapstructure:"type"`
+	Policies         []string               `hcl:"policies" mapstructure:"policies"`
+	MemberGroupNames []string               `hcl:"member_group_names" mapstructure:"member_group_names"`
+	Metadata         map[string]interface{} `hcl:"metadata" mapstructure:"metadata"`
+	Depends          []string               `hcl:"depends_on"`
+}
+
+func (g *IdentityGroup) Kind() string      { return "identity_group" }
+func (g *IdentityGroup) ID() string        { return g.Name }
+func (g *IdentityGroup) VaultPath() string { return fmt.Sprintf("identity/group/name/%s", g.Name) }
+
+func (g *IdentityGroup) DependsOn() []string { return g.Depends }
+func (g *IdentityGroup) policies() []string  { return g.Policies }
+
+func (g *IdentityGroup) references() []string {
+	var out []string
+	for _, m := range g.MemberGroupNames {
+		out = append(out, fmt.Sprintf("identity_group.%s", m))
+	}
+
+	return out
+}
+
+// Read returns the group with the IDs of its member groups converted to names
+func (g *IdentityGroup) Read(c *VCClient) (map[string]interface{}, bool, error) {
+	state, exists, err := c.readData(g.VaultPath())
+	if err != nil || !exists {
+		return nil, false, err
+	}
+	ids, _ := toStringSlice(state["member_group_ids"])
+	names, err := c.identityGroupNames(ids)
+	if err != nil {
+		return nil, false, err
+	}
+	state["member_group_names"] = names
+
+	return state, true, nil
+}
+
+func (g *IdentityGroup) Diff(state map[string]interface{}, exists bool) Change {
+	return diffResource(g.Kind(), g.ID(), g.VaultPath(), ConvertMapStringInterface(g), state, exists)
+}
+
+func (g *IdentityGroup) Create(c *VCClient) error {
+	return g.Update(c)
+}
+
+// Update writes the group, Vault only accepts member groups by ID so the
+// member group names are looked up first
+func (g *IdentityGroup) Update(c *VCClient) error {
+	data := ConvertMapStringInterface(g)
+	delete(data, "member_group_names")
+	if len(g.MemberGroupNames) > 0 {
+		var ids []string
+		for _, m := range g.MemberGroupNames {
+			id, err := c.identityGroupID(m)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+		data["member_group_ids"] = ids
+	}
+	if _, err := c.Logical().Write(g.VaultPath(), data); err != nil {
+		return fmt.Errorf("Error writing identity group: %s\nError: %v", g.Name, err)
+	}
+
+	return nil
+}
+
+func (g *IdentityGroup) Delete(c *VCClient) error {
+	if _, err := c.Logical().Delete(g.VaultPath()); err != nil {
+		return fmt.Errorf("Error deleting identity group: %s\nError: %v", g.Name, err)
+	}
+
+	return nil
+}
+
+func listIdentityGroups(c *VCClient, conf Config) ([]Resource, error) {
+	keys, err := c.listKeys("identity/group/name")
+	if err != nil {
+		return nil, err
+	}
+	var out []Resource
+	for _, k := range keys {
+		g := &IdentityGroup{Name: k}
+		state, exists, err := g.Read(c)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			continue
+		}
+		g.Type, _ = state["type"].(string)
+		g.Policies, _ = toStringSlice(state["policies"])
+		g.MemberGroupNames, _ = toStringSlice(state["member_group_names"])
+		if m, ok := state["metadata"].(map[string]interface{}); ok && len(m) > 0 {
+			g.Metadata = m
+		}
+		out = append(out, g)
+	}
+
+	return out, nil
+}
+
+// identityGroupID returns the ID of a group from its name
+func (c *VCClient) identityGroupID(name string) (string, error) {
+	data, exists, err := c.readData(fmt.Sprintf("identity/group/name/%s", name))
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", fmt.Errorf("Identity group does not exist: %s", name)
+	}
+	id, _ := data["id"].(string)
+
+	return id, nil
+}
+
+// identityGroupNames returns the names of groups from their IDs
+func (c *VCClient) identityGroupNames(ids []string) ([]interface{}, error) {
+	names := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		data, exists, err := c.readData(fmt.Sprintf("identity/group/id/%s", id))
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			names = append(names, data["name"])
+		}
+	}
+
+	return names, nil
+}
+
+// IdentityGroupAlias binds an external identity group to a group of an auth
+// backend, such as an LDAP group, the block key is the name of the group in
+// the auth backend and mount is the path of the backend
+type IdentityGroupAlias struct {
+	Name    string   `hcl:",key"`
+	Group   string   `hcl:"group" validate:"required"`
+	Mount   string   `hcl:"mount" validate:"required"`
+	Depends []string `hcl:"depends_on"`
+}
+
+func (a *IdentityGroupAlias) Kind() string { return "identity_group_alias" }
+func (a *IdentityGroupAlias) ID() string   { return a.Name }
+
+// VaultPath identifies the alias by its mount and name, Vault only
+// addresses aliases by their generated ID
+func (a *IdentityGroupAlias) VaultPath() string {
+	return fmt.Sprintf("identity/group-alias/%s/%s", a.mount(), a.Name)
+}
+
+func (a *IdentityGroupAlias) DependsOn() []string { return a.Depends }
+
+func (a *IdentityGroupAlias) references() []string {
+	return []string{fmt.Sprintf("identity_group.%s", a.Group), fmt.Sprintf("auth.%s", a.mount())}
+}
+
+func (a *IdentityGroupAlias) mount() string {
+	return strings.Trim(a.Mount, "/")
+}
+
+func (a *IdentityGroupAlias) Read(c *VCClient) (map[string]interface{}, bool, error) {
+	group, err := c.lookupGroupAlias(a.mount(), a.Name)
+	if err != nil || group == nil {
+		return nil, false, err
+	}
+
+	return map[string]interface{}{"group": group["name"]}, true, nil
+}
+
+func (a *IdentityGroupAlias) Diff(state map[string]interface{}, exists bool) Change {
+	return diffResource(a.Kind(), a.ID(), a.VaultPath(), map[string]interface{}{"group": a.Group}, state, exists)
+}
+
+func (a *IdentityGroupAlias) Create(c *VCClient) error {
+	return a.Update(c)
+}
+
+// Update creates the alias, or moves an existing alias to the group
+func (a *IdentityGroupAlias) Update(c *VCClient) error {
+	accessor, err := c.authAccessor(a.mount())
+	if err != nil {
+		return err
+	}
+	if accessor == "" {
+		return fmt.Errorf("Auth backend is not enabled: %s", a.mount())
+	}
+	id, err := c.identityGroupID(a.Group)
+	if err != nil {
+		return err
+	}
+	data := map[string]interface{}{
+		"name":           a.Name,
+		"mount_accessor": accessor,
+		"canonical_id":   id,
+	}
+	path := "identity/group-alias"
+	group, err := c.lookupGroupAlias(a.mount(), a.Name)
+	if err != nil {
+		return err
+	}
+	if aliasID := groupAliasID(group); aliasID != "" {
+		path = fmt.Sprintf("identity/group-alias/id/%s", aliasID)
+	}
+	if _, err := c.Logical().Write(path, data); err != nil {
+		return fmt.Errorf("Error writing identity group alias: %s\nError: %v", a.Name, err)
+	}
+
+	return nil
+}
+
+// Delete looks up the alias by its mount and name, so it is not deleted if
+// it has been renamed since it was listed
+func (a *IdentityGroupAlias) Delete(c *VCClient) error {
+	group, err := c.lookupGroupAlias(a.mount(), a.Name)
+	if err != nil {
+		return err
+	}
+	aliasID := groupAliasID(group)
+	if aliasID == "" {
+		return nil
+	}
+	if _, err := c.Logical().Delete(fmt.Sprintf("identity/group-alias/id/%s", aliasID)); err != nil {
+		return fmt.Errorf("Error deleting identity group alias: %s\nError: %v", a.Name, err)
+	}
+
+	return nil
+}
+
+func listIdentityGroupAliases(c *VCClient, conf Config) ([]Resource, error) {
+	s, err := c.Logical().List("identity/group-alias/id")
+	if err != nil {
+		return nil, fmt.Errorf("Error listing identity group aliases: %v", err)
+	}
+	if s == nil {
+		return nil, nil
+	}
+	paths, err := c.authAccessorPaths()
+	if err != nil {
+		return nil, err
+	}
+	info, _ := s.Data["key_info"].(map[string]interface{})
+	var out []Resource
+	for _, v := range info {
+		alias, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		accessor, _ := alias["mount_accessor"].(string)
+		canonicalID, _ := alias["canonical_id"].(string)
+		names, err := c.identityGroupNames([]string{canonicalID})
+		if err != nil {
+			return nil, err
+		}
+		if len(names) == 0 || paths[accessor] == "" {
+			continue
+		}
+		out = append(out, &IdentityGroupAlias{
+			Name:  fmt.Sprint(alias["name"]),
+			Group: fmt.Sprint(names[0]),
+			Mount: paths[accessor],
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].VaultPath() < out[j].VaultPath() })
+
+	return out, nil
+}
+
+// lookupGroupAlias returns the group that has an alias on an auth backend,
+// or nil if the backend is not enabled or there is no such alias
+func (c *VCClient) lookupGroupAlias(mount, name string) (map[string]interface{}, error) {
+	accessor, err := c.authAccessor(mount)
+	if err != nil || accessor == "" {
+		return nil, err
+	}
+	s, err := c.Logical().Write("identity/lookup/group", map[string]interface{}{
+		"alias_name":           name,
+		"alias_mount_accessor": accessor,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error looking up identity group alias: %s\nError: %v", name, err)
+	}
+	if s == nil {
+		return nil, nil
+	}
+
+	return s.Data, nil
+}
+
+// groupAliasID returns the ID of the alias of a group read from Vault
+func groupAliasID(group map[string]interface{}) string {
+	alias, _ := group["alias"].(map[string]interface{})
+	id, _ := alias["id"].(string)
+
+	return id
+}
+
+// IdentityEntity is an entity in the identity secrets engine, aliases tie
+// the entity to a user of an auth backend
+type IdentityEntity struct {
+	Name     string                 `hcl:",key"`
+	Policies []string               `hcl:"policies" mapstructure:"policies"`
+	Metadata map[string]interface{} `hcl:"metadata" mapstructure:"metadata"`
+	Disabled bool                   `hcl:"disabled" mapstructure:"disabled"`
+	Aliases  []IdentityEntityAlias  `hcl:"alias"`
+	Depends  []string               `hcl:"depends_on"`
+}
+
+// IdentityEntityAlias is the name of an entity's user on an auth backend,
+// the block key is the path of the backend as an entity has at most one
+// alias for each backend
+type IdentityEntityAlias struct {
+	Mount string `hcl:",key"`
+	Name  string `hcl:"name" validate:"required"`
+}
+
+func (e *IdentityEntity) Kind() string      { return "identity_entity" }
+func (e *IdentityEntity) ID() string        { return e.Name }
+func (e *IdentityEntity) VaultPath() string { return fmt.Sprintf("identity/entity/name/%s", e.Name) }
+
+func (e *IdentityEntity) DependsOn() []string { return e.Depends }
+func (e *IdentityEntity) policies() []string  { return e.Policies }
+
+func (e *IdentityEntity) references() []string {
+	var out []string
+	for _, a := range e.Aliases {
+		out = append(out, fmt.Sprintf("auth.%s", strings.Trim(a.Mount, "/")))
+	}
+
+	return out
+}
+
+// Read returns the entity with its aliases as a map of mount path to name
+func (e *IdentityEntity) Read(c *VCClient) (map[string]interface{}, bool, error) {
+	state, exists, err := c.readData(e.VaultPath())
+	if err != nil || !exists {
+		return nil, false, err
+	}
+	paths, err := c.authAccessorPaths()
+	if err != nil {
+		return nil, false, err
+	}
+	aliases := make(map[string]interface{})
+	for _, a := range objectMaps(state["aliases"]) {
+		if p, ok := paths[fmt.Sprint(a["mount_accessor"])]; ok {
+			aliases[p] = a["name"]
+		}
+	}
+	state["aliases"] = aliases
+
+	return state, true, nil
+}
+
+// Diff compares the entity with Vault, disabled is compared even when false
+// so an entity that is enabled again is updated
+func (e *IdentityEntity) Diff(state map[string]interface{}, exists bool) Change {
+	desired := ConvertMapStringInterface(e)
+	desired["disabled"] = e.Disabled
+	if len(e.Aliases) > 0 {
+		aliases := make(map[string]interface{})
+		for _, a := range e.Aliases {
+			aliases[strings.Trim(a.Mount, "/")] = a.Name
+		}
+		desired["aliases"] = aliases
+	}
+
+	return diffResource(e.Kind(), e.ID(), e.VaultPath(), desired, state, exists)
+}
+
+func (e *IdentityEntity) Create(c *VCClient) error {
+	return e.Update(c)
+}
+
+// Update writes the entity then its aliases, when aliases are declared any
+// alias on a backend that is not declared is removed
+func (e *IdentityEntity) Update(c *VCClient) error {
+	data := ConvertMapStringInterface(e)
+	data["disabled"] = e.Disabled
+	if _, err := c.Logical().Write(e.VaultPath(), data); err != nil {
+		return fmt.Errorf("Error writing identity entity: %s\nError: %v", e.Name, err)
+	}
+	if len(e.Aliases) == 0 {
+		return nil
+	}
+
+	state, exists, err := c.readData(e.VaultPath())
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("Identity entity does not exist after writing: %s", e.Name)
+	}
+	id, _ := state["id"].(string)
+	current := make(map[string]map[string]interface{})
+	for _, a := range objectMaps(state["aliases"]) {
+		current[fmt.Sprint(a["mount_accessor"])] = a
+	}
+
+	declared := make(map[string]bool)
+	for _, a := range e.Aliases {
+		mount := strings.Trim(a.Mount, "/")
+		accessor, err := c.authAccessor(mount)
+		if err != nil {
+			return err
+		}
+		if accessor == "" {
+			return fmt.Errorf("Auth backend is not enabled: %s", mount)
+		}
+		declared[accessor] = true
+		path := "identity/entity-alias"
+		if cur, ok := current[accessor]; ok {
+			if cur["name"] == a.Name {
+				continue
+			}
+			path = fmt.Sprintf("identity/entity-alias/id/%s", cur["id"])
+		}
+		_, err = c.Logical().Write(path, map[string]interface{}{
+			"name":           a.Name,
+			"mount_accessor": accessor,
+			"canonical_id":   id,
+		})
+		if err != nil {
+			return fmt.Errorf("Error writing identity entity alias: %s\nError: %v", a.Name, err)
+		}
+	}
+	for accessor, cur := range current {
+		if declared[accessor] {
+			continue
+		}
+		if _, err := c.Logical().Delete(fmt.Sprintf("identity/entity-alias/id/%s", cur["id"])); err != nil {
+			return fmt.Errorf("Error deleting identity entity alias: %s\nError: %v", cur["name"], err)
+		}
+	}
+
+	return nil
+}
+
+func (e *IdentityEntity) Delete(c *VCClient) error {
+	if _, err := c.Logical().Delete(e.VaultPath()); err != nil {
+		return fmt.Errorf("Error deleting identity entity: %s\nError: %v", e.Name, err)
+	}
+
+	return nil
+}
+
+func listIdentityEntities(c *VCClient, conf Config) ([]Resource, error) {
+	keys, err := c.listKeys("identity/entity/name")
+	if err != nil {
+		return nil, err
+	}
+	var out []Resource
+	for _, k := range keys {
+		e := &IdentityEntity{Name: k}
+		state, exists, err := e.Read(c)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			continue
+		}
+		e.Policies, _ = toStringSlice(state["policies"])
+		e.Disabled, _ = state["disabled"].(bool)
+		if m, ok := state["metadata"].(map[string]interface{}); ok && len(m) > 0 {
+			e.Metadata = m
+		}
+		aliases, _ := state["aliases"].(map[string]interface{})
+		for mount, name := range aliases {
+			e.Aliases = append(e.Aliases, IdentityEntityAlias{Mount: mount, Name: fmt.Sprint(name)})
+		}
+		sort.Slice(e.Aliases, func(i, j int) bool { return e.Aliases[i].Mount < e.Aliases[j].Mount })
+		out = append(out, e)
+	}
+
+	return out, nil
+}
+
+// authAccessor returns the accessor of the auth backend at a path, or an
+// empty string if no backend is enabled there
+func (c *VCClient) authAccessor(path string) (string, error) {
+	auth, err := c.currentAuth()
+	if err != nil {
+		return "", err
+	}
+	if a, ok := auth[path+"/"]; ok {
+		return a.Accessor, nil
+	}
+	if c.snap == nil {
+		return "", nil
+	}
+	// backends enabled during an apply are not in the snapshot
+	auth, err = c.Sys().ListAuth()
+	if err != nil {
+		return "", fmt.Errorf("Error listing auth backends: %v", err)
+	}
+	if a, ok := auth[path+"/"]; ok {
+		return a.Accessor, nil
+	}
+
+	return "", nil
+}
+
+// authAccessorPaths maps the accessor of every auth backend to its path
+func (c *VCClient) authAccessorPaths() (map[string]string, error) {
+	auth, err := c.currentAuth()
+	if err != nil {
+		return nil, err
+	}
+	paths := make(map[string]string, len(auth))
+	for p, a := range auth {
+		paths[a.Accessor] = strings.TrimSuffix(p, "/")
+	}
+
+	return paths, nil
+}
@@ -0,0 +1,399 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+)
+
+const identityConfig = `
+auth {
+  ldap {
+    authconfig {
+      url = "ldaps://corp.example.com"
+    }
+  }
+  userpass {}
+}
+
+policy "admin" {
+  rules = "path \"*\" { capabilities = [\"sudo\"] }"
+}
+
+identity_group "ldap-admins" {
+  type = "external"
+}
+
+identity_group "admins" {
+  policies = ["admin"]
+  member_group_names = ["ldap-admins"]
+  metadata {
+    owner = "platform"
+  }
+}
+
+identity_group_alias "cn=admins,ou=groups" {
+  group = "ldap-admins"
+  mount = "ldap"
+}
+
+identity_entity "alice" {
+  policies = ["admin"]
+  alias "userpass" {
+    name = "alice"
+  }
+  alias "ldap" {
+    name = "alice.smith"
+  }
+}
+`
+
+// identityResources returns the resources of identityConfig in the order
+// they are applied
+func identityResources(t *testing.T) ([]Resource, []string) {
+	conf, err := ParseConfig([]byte(identityConfig))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	resources, err := conf.AllResources()
+	assert.NoError(t, err, "Sorting resources should return no error: %v", err)
+
+	return resources, refs(resources)
+}
+
+func TestIdentity_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   []string
+	}{
+		{name: "valid", config: identityConfig},
+		{
+			name: "alias without mount",
+			config: `identity_group_alias "admins" {
+  group = "ldap-admins"
+}`,
+			want: []string{"identity.vc:1:1: identity_group_alias is missing required field: mount"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, validationMessages(Validate([]ConfigFile{{Name: "identity.vc", Data: []byte(tt.config)}})),
+				"Identity config should be validated")
+		})
+	}
+}
+
+func TestIdentity_Order(t *testing.T) {
+	_, order := identityResources(t)
+	tests := []struct {
+		before string
+		after  string
+	}{
+		{"identity_group.ldap-admins", "identity_group.admins"},
+		{"policy.admin", "identity_group.admins"},
+		{"auth.ldap", "identity_group_alias.cn=admins,ou=groups"},
+		{"identity_group.ldap-admins", "identity_group_alias.cn=admins,ou=groups"},
+		{"auth.userpass", "identity_entity.alice"},
+		{"auth.ldap", "identity_entity.alice"},
+	}
+	for _, tt := range tests {
+		assert.True(t, indexOf(order, tt.before) < indexOf(order, tt.after), "%s should be written after %s", tt.after, tt.before)
+	}
+}
+
+func TestIdentity_Apply(t *testing.T) {
+	f, c := newFakeVault(t)
+	defer f.Close()
+	fi := newFakeIdentity(f)
+	conf, err := ParseConfig([]byte(identityConfig))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	applyAndPlan(t, c, conf)
+
+	ldap := f.get("sys/auth/ldap")["accessor"]
+	userpass := f.get("sys/auth/userpass")["accessor"]
+	external := idByName(fi.groups, "ldap-admins")
+	admins := fi.groups[idByName(fi.groups, "admins")]
+	assert.Equal(t, "external", fi.groups[external]["type"], "Groups should be written with their type")
+	assert.Equal(t, []interface{}{external}, admins["member_group_ids"], "Member groups should be written by ID")
+	assert.Equal(t, []interface{}{"admin"}, admins["policies"], "Group policies should be written")
+	if assert.Len(t, fi.groupAliases, 1, "The group alias should be created") {
+		for _, a := range fi.groupAliases {
+			assert.Equal(t, map[string]interface{}{"name": "cn=admins,ou=groups", "mount_accessor": ldap, "canonical_id": external},
+				map[string]interface{}{"name": a["name"], "mount_accessor": a["mount_accessor"], "canonical_id": a["canonical_id"]},
+				"The alias should bind the backend's group to the external group")
+		}
+	}
+	entity := idByName(fi.entities, "alice")
+	aliases := make(map[interface{}]interface{})
+	for _, a := range fi.entityAliases {
+		assert.Equal(t, entity, a["canonical_id"], "Aliases should belong to the entity")
+		aliases[a["mount_accessor"]] = a["name"]
+	}
+	assert.Equal(t, map[interface{}]interface{}{userpass: "alice", ldap: "alice.smith"}, aliases, "Entity aliases should be created")
+
+	// applying again updates groups, aliases and entities in place, an
+	// entity disabled in Vault is enabled again and aliases that are not
+	// declared are removed
+	ids := fi.ids
+	fi.entities[entity]["disabled"] = true
+	conf.Resources[len(conf.Resources)-1].(*IdentityEntity).Aliases = []IdentityEntityAlias{{Mount: "userpass", Name: "alice"}}
+	applyAndPlan(t, c, conf)
+	assert.Equal(t, ids, fi.ids, "Applying again should not recreate groups, aliases or entities")
+	assert.Equal(t, false, fi.entities[entity]["disabled"], "The entity should be enabled again")
+	assert.Len(t, fi.entityAliases, 1, "Aliases that are not declared should be removed")
+}
+
+func TestIdentityEntity_Diff(t *testing.T) {
+	resources, order := identityResources(t)
+	e := resources[indexOf(order, "identity_entity.alice")]
+
+	aliases := map[string]interface{}{"userpass": "alice", "ldap": "alice.smith"}
+	tests := []struct {
+		name     string
+		aliases  map[string]interface{}
+		disabled bool
+		action   ChangeAction
+	}{
+		{name: "unchanged", aliases: aliases, action: ActionNoop},
+		{name: "missing alias", aliases: map[string]interface{}{"userpass": "alice"}, action: ActionUpdate},
+		{name: "renamed alias", aliases: map[string]interface{}{"userpass": "alice", "ldap": "asmith"}, action: ActionUpdate},
+		{name: "disabled in Vault", aliases: aliases, disabled: true, action: ActionUpdate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := map[string]interface{}{
+				"name":     "alice",
+				"policies": []interface{}{"admin"},
+				"disabled": tt.disabled,
+				"aliases":  tt.aliases,
+			}
+			assert.Equal(t, tt.action, e.Diff(state, true).Action, "Entities should be compared with their aliases")
+		})
+	}
+}
+
+func TestIdentity_JSON(t *testing.T) {
+	conf, err := ParseConfig([]byte(identityConfig))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+
+	// plans are saved as JSON, identity resources should survive encoding
+	b, err := json.Marshal(conf)
+	assert.NoError(t, err, "Encoding config should return no error: %v", err)
+	var decoded Config
+	assert.NoError(t, json.Unmarshal(b, &decoded), "Decoding config should return no error")
+	assert.Equal(t, conf.Resources, decoded.Resources, "Identity resources should survive encoding")
+}
+
+func TestAuthAccessor(t *testing.T) {
+	f, c := newFakeVault(t)
+	defer f.Close()
+	assert.NoError(t, c.Sys().EnableAuthWithOptions("ldap", &api.EnableAuthOptions{Type: "ldap"}), "Enabling auth should not return an error")
+	s, err := c.withSnapshot()
+	assert.NoError(t, err, "Taking a snapshot should not return an error: %v", err)
+	assert.NoError(t, c.Sys().EnableAuthWithOptions("userpass", &api.EnableAuthOptions{Type: "userpass"}), "Enabling auth should not return an error")
+
+	tests := []struct {
+		name     string
+		client   *VCClient
+		path     string
+		accessor bool
+	}{
+		{name: "existing backend", client: c, path: "ldap", accessor: true},
+		{name: "missing backend", client: c, path: "okta"},
+		{name: "backend in the snapshot", client: s, path: "ldap", accessor: true},
+		{name: "backend enabled after the snapshot", client: s, path: "userpass", accessor: true},
+		{name: "missing backend with a snapshot", client: s, path: "okta"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accessor, err := tt.client.authAccessor(tt.path)
+			assert.NoError(t, err, "Missing backends should not return an error: %v", err)
+			assert.Equal(t, tt.accessor, accessor != "", "Only existing backends should have an accessor: %q", accessor)
+		})
+	}
+}
+
+// fakeIdentity emulates the identity secrets engine on a fake server,
+// groups and entities are stored by ID and are returned with their aliases
+type fakeIdentity struct {
+	groups        map[string]map[string]interface{}
+	groupAliases  map[string]map[string]interface{}
+	entities      map[string]map[string]interface{}
+	entityAliases map[string]map[string]interface{}
+	ids           int
+}
+
+func newFakeIdentity(f *fakeVault) *fakeIdentity {
+	fi := &fakeIdentity{
+		groups:        make(map[string]map[string]interface{}),
+		groupAliases:  make(map[string]map[string]interface{}),
+		entities:      make(map[string]map[string]interface{}),
+		entityAliases: make(map[string]map[string]interface{}),
+	}
+	f.handlePrefix("identity", fi.serve)
+
+	return fi
+}
+
+func (fi *fakeIdentity) serve(method, path string, body map[string]interface{}) map[string]interface{} {
+	path = strings.TrimPrefix(path, "identity/")
+	switch {
+	case path == "group/name" || path == "entity/name":
+		return identityKeys(fi.objects(path), "name")
+	case strings.HasPrefix(path, "group/name/") || strings.HasPrefix(path, "entity/name/"):
+		objs := fi.objects(path)
+		name := path[strings.LastIndex(path, "/")+1:]
+		id := idByName(objs, name)
+		switch method {
+		case http.MethodGet:
+			return fi.read(objs, id)
+		case http.MethodDelete:
+			delete(objs, id)
+			return nil
+		}
+		body["name"] = name
+		if t, _ := body["type"].(string); id == "" && t == "" && strings.HasPrefix(path, "group/") {
+			body["type"] = "internal"
+		}
+		return fi.write(objs, id, body)
+	case strings.HasPrefix(path, "group/id/"):
+		return fi.read(fi.groups, strings.TrimPrefix(path, "group/id/"))
+	case path == "group-alias/id" && method == "LIST":
+		keys := identityKeys(fi.groupAliases, "id")
+		if keys == nil {
+			return nil
+		}
+		info := make(map[string]interface{})
+		for id, a := range fi.groupAliases {
+			info[id] = a
+		}
+		keys["key_info"] = info
+		return keys
+	case path == "group-alias" || path == "entity-alias":
+		return fi.write(fi.objects(path), "", body)
+	case strings.HasPrefix(path, "group-alias/id/") || strings.HasPrefix(path, "entity-alias/id/"):
+		objs := fi.objects(path)
+		id := path[strings.LastIndex(path, "/")+1:]
+		if method == http.MethodDelete {
+			delete(objs, id)
+			return nil
+		}
+		return fi.write(objs, id, body)
+	case path == "lookup/group":
+		for _, a := range fi.groupAliases {
+			if a["name"] == body["alias_name"] && a["mount_accessor"] == body["alias_mount_accessor"] {
+				return fi.read(fi.groups, a["canonical_id"].(string))
+			}
+		}
+	}
+
+	return nil
+}
+
+// objects returns the groups, entities or aliases a path belongs to
+func (fi *fakeIdentity) objects(path string) map[string]map[string]interface{} {
+	switch {
+	case strings.HasPrefix(path, "group/"):
+		return fi.groups
+	case strings.HasPrefix(path, "group-alias"):
+		return fi.groupAliases
+	case strings.HasPrefix(path, "entity/"):
+		return fi.entities
+	}
+
+	return fi.entityAliases
+}
+
+// write creates an object when id is empty, or updates the fields of the
+// object that are written
+func (fi *fakeIdentity) write(objs map[string]map[string]interface{}, id string, body map[string]interface{}) map[string]interface{} {
+	if id == "" {
+		fi.ids++
+		id = fmt.Sprintf("id-%d", fi.ids)
+		objs[id] = map[string]interface{}{"id": id}
+	}
+	if _, ok := objs[id]; !ok {
+		return nil
+	}
+	for k, v := range body {
+		objs[id][k] = v
+	}
+
+	return map[string]interface{}{"id": id}
+}
+
+// read returns a group with its alias or an entity with its aliases
+func (fi *fakeIdentity) read(objs map[string]map[string]interface{}, id string) map[string]interface{} {
+	obj, ok := objs[id]
+	if !ok {
+		return nil
+	}
+	out := make(map[string]interface{}, len(obj)+1)
+	for k, v := range obj {
+		out[k] = v
+	}
+	if _, ok := fi.groups[id]; ok {
+		out["alias"] = map[string]interface{}{}
+		for _, a := range fi.groupAliases {
+			if a["canonical_id"] == id {
+				out["alias"] = a
+			}
+		}
+		return out
+	}
+	var aliases []interface{}
+	for _, a := range fi.entityAliases {
+		if a["canonical_id"] == id {
+			aliases = append(aliases, a)
+		}
+	}
+	out["aliases"] = aliases
+
+	return out
+}
+
+// identityKeys returns the names or IDs of objects as keys
+func identityKeys(objs map[string]map[string]interface{}, field string) map[string]interface{} {
+	var keys []interface{}
+	for _, obj := range objs {
+		keys = append(keys, obj[field])
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return map[string]interface{}{"keys": keys}
+}
+
+// idByName returns the ID of the object with a name
+func idByName(objs map[string]map[string]interface{}, name string) string {
+	for id, obj := range objs {
+		if obj["name"] == name {
+			return id
+		}
+	}
+
+	return ""
+}
+
+func (vsc *vaultServerConfigTestSuite) TestVCClient_Identity() {
+	conf, err := ParseConfig([]byte(identityConfig))
+	assert.NoError(vsc.T(), err, "Parsing config should not return an error: %v", err)
+	_, err = vsc.vtc.Apply(context.Background(), conf)
+	assert.NoError(vsc.T(), err, "Apply should not return an error: %v", err)
+
+	group, err := vsc.vtc.lookupGroupAlias("ldap", "cn=admins,ou=groups")
+	assert.NoError(vsc.T(), err, "Looking up group alias should not return an error: %v", err)
+	assert.Equal(vsc.T(), "ldap-admins", group["name"], "Alias should be bound to the external group")
+
+	applyAndPlan(vsc.T(), vsc.vtc, conf, "identity_group", "identity_group_alias", "identity_entity")
+	again, err := vsc.vtc.lookupGroupAlias("ldap", "cn=admins,ou=groups")
+	assert.NoError(vsc.T(), err, "Looking up group alias should not return an error: %v", err)
+	assert.Equal(vsc.T(), group["id"], again["id"], "Applying again should not recreate the group")
+	assert.Equal(vsc.T(), groupAliasID(group), groupAliasID(again), "Applying again should not recreate the alias")
+}
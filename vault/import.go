@@ -0,0 +1,336 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Import reads the configuration of the Vault server into a Config, system
+// mounts, the root and default policies and auth backends that are not
+// supported are not included
+func (c *VCClient) Import() (*Config, error) {
+	conf := &Config{}
+	importers := []func(*Config) error{
+		c.importMounts,
+		c.importPolicies,
+		c.importTokenRoles,
+		c.importAuth,
+		c.importResources,
+	}
+	for _, f := range importers {
+		if err := f(conf); err != nil {
+			return nil, err
+		}
+	}
+
+	return conf, nil
+}
+
+// importResources imports the blocks of registered types
+func (c *VCClient) importResources(conf *Config) error {
+	for _, t := range registry {
+		if t.New == nil {
+			continue
+		}
+		var blocks []Block
+		switch {
+		case t.Import != nil:
+			imported, err := t.Import(c, *conf)
+			if err != nil {
+				return err
+			}
+			blocks = imported
+		case t.List != nil:
+			resources, err := t.List(c, *conf)
+			if err != nil {
+				return err
+			}
+			for _, r := range resources {
+				blocks = append(blocks, r)
+			}
+		}
+		for _, b := range blocks {
+			if !contains(t.Protected, b.ID()) {
+				conf.Resources = append(conf.Resources, b)
+			}
+		}
+	}
+
+	return nil
+}
+
+// formatTTL converts a TTL in seconds into a duration string
+func formatTTL(seconds int) string {
+	switch {
+	case seconds == 0:
+		return ""
+	case seconds%3600 == 0:
+		return fmt.Sprintf("%dh", seconds/3600)
+	case seconds%60 == 0:
+		return fmt.Sprintf("%dm", seconds/60)
+	}
+
+	return (time.Duration(seconds) * time.Second).String()
+}
+
+// cleanOptions removes empty values from data read from Vault, along with
+// any keys that are not configurable
+func cleanOptions(data map[string]interface{}, drop ...string) map[string]interface{} {
+	out := make(map[string]interface{})
+	for k, v := range data {
+		if contains(drop, k) {
+			continue
+		}
+		switch t := v.(type) {
+		case nil:
+			continue
+		case string:
+			if t == "" {
+				continue
+			}
+		case bool:
+			if !t {
+				continue
+			}
+		case json.Number:
+			if t.String() == "0" {
+				continue
+			}
+		case []interface{}:
+			if len(t) == 0 {
+				continue
+			}
+		case map[string]interface{}:
+			if len(t) == 0 {
+				continue
+			}
+		}
+		out[k] = v
+	}
+
+	return out
+}
+
+func (c *VCClient) importMounts(conf *Config) error {
+	mounts, err := c.currentMounts()
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(mounts))
+	for k := range mounts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if contains(lookupType("mount").Protected, strings.TrimSuffix(k, "/")) {
+			continue
+		}
+		mo := mounts[k]
+		var m Mount
+		m.Path = strings.TrimSuffix(k, "/")
+		m.Name = strings.Replace(m.Path, "/", "_", -1)
+		m.Config.PathType = mo.Type
+		m.Config.Description = mo.Description
+		if len(mo.Options) > 0 {
+			m.Config.Options = make(map[string]interface{}, len(mo.Options))
+			for o, v := range mo.Options {
+				m.Config.Options[o] = v
+			}
+		}
+		m.Config.MountConfig.DefaultLeaseTTL = formatTTL(mo.Config.DefaultLeaseTTL)
+		m.Config.MountConfig.MaxLeaseTTL = formatTTL(mo.Config.MaxLeaseTTL)
+		if mo.Type == "kv" && mo.Options["version"] == "2" {
+			if m.Metadata, err = c.importKVConfig(m.Path); err != nil {
+				return err
+			}
+		}
+		conf.Mounts = append(conf.Mounts, m)
+	}
+
+	return nil
+}
+
+// importKVConfig reads the settings of a KV version 2 mount, nil is
+// returned if they are all the defaults
+func (c *VCClient) importKVConfig(path string) (*KVConfig, error) {
+	data, exists, err := c.readData(fmt.Sprintf("%s/config", path))
+	if err != nil || !exists {
+		return nil, err
+	}
+	var kv KVConfig
+	if v, ok := ttlSeconds(data["max_versions"]); ok && v != 0 {
+		maxVersions := int(v)
+		kv.MaxVersions = &maxVersions
+	}
+	if cas, _ := data["cas_required"].(bool); cas {
+		kv.CASRequired = &cas
+	}
+	if v, ok := ttlSeconds(data["delete_version_after"]); ok && v != 0 {
+		after := formatTTL(int(v))
+		kv.DeleteVersionAfter = &after
+	}
+	if kv == (KVConfig{}) {
+		return nil, nil
+	}
+
+	return &kv, nil
+}
+
+func (c *VCClient) importPolicies(conf *Config) error {
+	policies, err := c.currentPolicies()
+	if err != nil {
+		return err
+	}
+	sort.Strings(policies)
+
+	for _, p := range policies {
+		if contains(lookupType("policy").Protected, p) {
+			continue
+		}
+		rules, err := c.Sys().GetPolicy(p)
+		if err != nil {
+			return fmt.Errorf("Error reading policy: %s\nError: %v", p, err)
+		}
+		conf.Policies = append(conf.Policies, Policy{Name: p, Rules: rules})
+	}
+
+	return nil
+}
+
+func (c *VCClient) importTokenRoles(conf *Config) error {
+	roles, err := c.listKeys("auth/token/roles")
+	if err != nil {
+		return err
+	}
+
+	for _, r := range roles {
+		data, _, err := c.readData(fmt.Sprintf("auth/token/roles/%s", r))
+		if err != nil {
+			return err
+		}
+		conf.TokenRoles = append(conf.TokenRoles, TokenRole{
+			Name:    r,
+			Options: cleanOptions(data, "name"),
+		})
+	}
+
+	return nil
+}
+
+// importEntries reads every user or group mapping beneath a path
+func (c *VCClient) importEntries(path string) ([]AuthEntry, error) {
+	var entries []AuthEntry
+	names, err := c.listKeys(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range names {
+		data, _, err := c.readData(fmt.Sprintf("%s/%s", path, n))
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, AuthEntry{Name: n, Options: cleanOptions(data)})
+	}
+
+	return entries, nil
+}
+
+// importAuthTune reads the tuning options of an auth backend, options that
+// are set to their defaults are left out
+func (c *VCClient) importAuthTune(path string) (AuthMountConfig, error) {
+	var mc AuthMountConfig
+	s, err := c.Logical().Read(fmt.Sprintf("sys/auth/%s/tune", path))
+	if err != nil {
+		return mc, fmt.Errorf("Error reading auth tuning: %s\nError: %v", path, err)
+	}
+	if s == nil {
+		return mc, nil
+	}
+	if v, ok := ttlSeconds(s.Data["default_lease_ttl"]); ok {
+		mc.DefaultLeaseTTL = formatTTL(int(v))
+	}
+	if v, ok := ttlSeconds(s.Data["max_lease_ttl"]); ok {
+		mc.MaxLeaseTTL = formatTTL(int(v))
+	}
+	mc.ListingVisibility, _ = s.Data["listing_visibility"].(string)
+	if v, _ := s.Data["token_type"].(string); v != "default-service" {
+		mc.TokenType = v
+	}
+	mc.AuditNonHMACRequestKeys, _ = toStringSlice(s.Data["audit_non_hmac_request_keys"])
+	mc.AuditNonHMACResponseKeys, _ = toStringSlice(s.Data["audit_non_hmac_response_keys"])
+	mc.PassthroughRequestHeaders, _ = toStringSlice(s.Data["passthrough_request_headers"])
+	mc.AllowedResponseHeaders, _ = toStringSlice(s.Data["allowed_response_headers"])
+
+	return mc, nil
+}
+
+func (c *VCClient) importAuth(conf *Config) error {
+	auths, err := c.currentAuth()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(auths))
+	for k := range auths {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		ao := auths[k]
+		path := strings.TrimSuffix(k, "/")
+		// backends mounted at the name of their type need no name or path
+		name := ""
+		if path != ao.Type {
+			name = path
+		}
+		switch ao.Type {
+		case "ldap":
+			mc, err := c.importAuthTune(path)
+			if err != nil {
+				return err
+			}
+			l := &Ldap{Name: name, Path: name, Description: ao.Description, MountConfig: mc}
+			data, _, err := c.readData(fmt.Sprintf("%s/config", Path(l)))
+			if err != nil {
+				return err
+			}
+			l.AuthConfig = cleanOptions(data)
+			if l.Users, err = c.importEntries(l.userPath()); err != nil {
+				return err
+			}
+			if l.Groups, err = c.importEntries(l.groupPath()); err != nil {
+				return err
+			}
+			conf.Auth.Ldap = append(conf.Auth.Ldap, l)
+		case "github":
+			mc, err := c.importAuthTune(path)
+			if err != nil {
+				return err
+			}
+			g := &Github{Name: name, Path: name, Description: ao.Description, MountConfig: mc}
+			data, _, err := c.readData(fmt.Sprintf("%s/config", Path(g)))
+			if err != nil {
+				return err
+			}
+			g.AuthConfig = cleanOptions(data)
+			if g.Users, err = c.importEntries(g.userPath()); err != nil {
+				return err
+			}
+			teams, err := c.importEntries(g.groupPath())
+			if err != nil {
+				return err
+			}
+			for _, t := range teams {
+				g.Groups = append(g.Groups, GithubTeam{Name: t.Name, Options: t.Options})
+			}
+			conf.Auth.Github = append(conf.Auth.Github, g)
+		}
+	}
+
+	return nil
+}
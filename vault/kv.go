@@ -0,0 +1,260 @@
+package vault
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+)
+
+func init() {
+	Register(ResourceType{Name: "kv_config", FromConfig: kvConfigResources})
+	Register(ResourceType{Name: "secret_metadata", FromConfig: secretMetadataResources})
+}
+
+// kvMount returns the path of the mount a path is beneath and its KV
+// version, paths that are not beneath a KV version 2 mount are version 1
+func (c *VCClient) kvMount(path string) (string, int, error) {
+	mounts, err := c.refreshMounts()
+	if err != nil {
+		return "", 0, err
+	}
+	mount, mo := findMount(mounts, path)
+	if mo != nil && mo.Type == "kv" && mo.Options["version"] == "2" {
+		return mount, 2, nil
+	}
+
+	return mount, 1, nil
+}
+
+// findMount returns the mount a path is beneath, or nil if there is none
+func findMount(mounts map[string]*api.MountOutput, path string) (string, *api.MountOutput) {
+	var mount string
+	var out *api.MountOutput
+	for k, mo := range mounts {
+		if strings.HasPrefix(path+"/", k) && len(k) > len(mount) {
+			mount, out = k, mo
+		}
+	}
+
+	return strings.TrimSuffix(mount, "/"), out
+}
+
+// kvPath returns the path of a secret beneath a KV version 2 mount with a
+// prefix such as data or metadata, e.g. secret/app becomes secret/data/app
+func kvPath(mount, path, prefix string) string {
+	rel := strings.Trim(strings.TrimPrefix(path, mount), "/")
+	if rel == "" {
+		return fmt.Sprintf("%s/%s", mount, prefix)
+	}
+
+	return fmt.Sprintf("%s/%s/%s", mount, prefix, rel)
+}
+
+// ReadSecret returns the data of a secret and whether it exists, secrets in
+// KV version 2 mounts are read from the latest version
+func (c *VCClient) ReadSecret(path string) (map[string]interface{}, bool, error) {
+	mount, version, err := c.kvMount(path)
+	if err != nil {
+		return nil, false, err
+	}
+	if version == 1 {
+		return c.readData(path)
+	}
+	data, exists, err := c.readData(kvPath(mount, path, "data"))
+	if err != nil || !exists {
+		return nil, false, err
+	}
+	// the latest version of a deleted secret has no data
+	secret, ok := data["data"].(map[string]interface{})
+	if !ok {
+		return nil, false, nil
+	}
+
+	return secret, true, nil
+}
+
+// writeSecret writes the data of a secret, creating a new version of
+// secrets in KV version 2 mounts
+func (c *VCClient) writeSecret(path string, data map[string]interface{}) error {
+	mount, version, err := c.kvMount(path)
+	if err != nil {
+		return err
+	}
+	if version == 2 {
+		path = kvPath(mount, path, "data")
+		data = map[string]interface{}{"data": data}
+	}
+	if _, err := c.Logical().Write(path, data); err != nil {
+		return fmt.Errorf("Error writing Vault path: %s\nError: %v", path, err)
+	}
+
+	return nil
+}
+
+// deleteSecret deletes a secret, every version of secrets in KV version 2
+// mounts is removed
+func (c *VCClient) deleteSecret(path string) error {
+	mount, version, err := c.kvMount(path)
+	if err != nil {
+		return err
+	}
+	if version == 2 {
+		path = kvPath(mount, path, "metadata")
+	}
+	if _, err := c.Logical().Delete(path); err != nil {
+		return fmt.Errorf("Error deleting Vault path: %s\nError: %v", path, err)
+	}
+
+	return nil
+}
+
+// walkSecrets returns the path of every secret beneath a path, secrets in
+// KV version 2 mounts are listed from their metadata
+func (c *VCClient) walkSecrets(path string) ([]string, error) {
+	mount, version, err := c.kvMount(path)
+	if err != nil {
+		return nil, err
+	}
+	if version == 1 {
+		return c.walkKeys(path)
+	}
+	prefix := kvPath(mount, "", "metadata")
+	keys, err := c.walkKeys(kvPath(mount, path, "metadata"))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, fmt.Sprintf("%s/%s", mount, strings.TrimPrefix(k, prefix+"/")))
+	}
+
+	return out, nil
+}
+
+// KVConfig holds the settings of a KV version 2 mount written to
+// <mount>/config, they are the defaults for every secret in the mount.
+// Settings are pointers so one declared as its default, e.g.
+// max_versions = 0, is written to reset it
+type KVConfig struct {
+	MaxVersions        *int    `hcl:"max_versions" mapstructure:"max_versions"`
+	CASRequired        *bool   `hcl:"cas_required" mapstructure:"cas_required"`
+	DeleteVersionAfter *string `hcl:"delete_version_after" mapstructure:"delete_version_after"`
+}
+
+// SecretMetadata holds the settings of a secret in a KV version 2 mount
+// written to <mount>/metadata/<path>, see KVConfig
+type SecretMetadata struct {
+	MaxVersions        *int                   `hcl:"max_versions" mapstructure:"max_versions"`
+	CASRequired        *bool                  `hcl:"cas_required" mapstructure:"cas_required"`
+	DeleteVersionAfter *string                `hcl:"delete_version_after" mapstructure:"delete_version_after"`
+	CustomMetadata     map[string]interface{} `hcl:"custom_metadata" mapstructure:"custom_metadata"`
+}
+
+// kvSettings returns the declared settings to write, an empty
+// delete_version_after is written as 0s which Vault returns when it is off
+func kvSettings(v interface{}) map[string]interface{} {
+	data := ConvertMapStringInterface(v)
+	if data["delete_version_after"] == "" {
+		data["delete_version_after"] = "0s"
+	}
+
+	return data
+}
+
+func kvConfigResources(conf Config) ([]Resource, error) {
+	var out []Resource
+	for _, m := range conf.Mounts {
+		if m.Metadata == nil {
+			continue
+		}
+		path := strings.Trim(m.Path, "/")
+		out = append(out, &pathResource{
+			kind:  "kv_config",
+			name:  m.Name,
+			path:  fmt.Sprintf("%s/config", path),
+			data:  kvSettings(m.Metadata),
+			mount: path,
+		})
+	}
+
+	return out, nil
+}
+
+// secretMetadataResource is the metadata of a secret in a KV version 2
+// mount, it is identified by the path of the secret and is read from and
+// written to the metadata path of the mount
+type secretMetadataResource struct {
+	pathResource
+}
+
+func secretMetadataResources(conf Config) ([]Resource, error) {
+	var out []Resource
+	for _, s := range conf.Secrets {
+		if s.Metadata == nil {
+			continue
+		}
+		out = append(out, &secretMetadataResource{pathResource{
+			kind: "secret_metadata",
+			name: s.Name,
+			path: strings.Trim(s.Path, "/"),
+			data: kvSettings(s.Metadata),
+		}})
+	}
+
+	return out, nil
+}
+
+// metadataPath returns the metadata path of the secret, it is an error
+// if the secret is not in a KV version 2 mount
+func (r *secretMetadataResource) metadataPath(c *VCClient) (string, error) {
+	mount, version, err := c.kvMount(r.path)
+	if err != nil {
+		return "", err
+	}
+	if version != 2 {
+		return "", fmt.Errorf("Secret metadata can only be written to KV version 2 mounts: %s", r.path)
+	}
+
+	return kvPath(mount, r.path, "metadata"), nil
+}
+
+// Read returns the metadata of the secret, metadata does not exist for
+// secrets that are not in a KV version 2 mount, such as secrets in a mount
+// that has not been created yet
+func (r *secretMetadataResource) Read(c *VCClient) (map[string]interface{}, bool, error) {
+	mount, version, err := c.kvMount(r.path)
+	if err != nil || version != 2 {
+		return nil, false, err
+	}
+
+	return c.readData(kvPath(mount, r.path, "metadata"))
+}
+
+func (r *secretMetadataResource) Create(c *VCClient) error {
+	return r.Update(c)
+}
+
+func (r *secretMetadataResource) Update(c *VCClient) error {
+	path, err := r.metadataPath(c)
+	if err != nil {
+		return err
+	}
+	data := make(map[string]interface{}, len(r.data))
+	for k, v := range r.data {
+		data[k] = v
+	}
+	if m, ok := data["custom_metadata"].(map[string]interface{}); ok {
+		data["custom_metadata"] = stringMap(m)
+	}
+	if _, err := c.Logical().Write(path, data); err != nil {
+		return fmt.Errorf("Error writing secret metadata: %s\nError: %v", r.path, err)
+	}
+
+	return nil
+}
+
+// Delete removes the metadata of the secret along with every version of it
+func (r *secretMetadataResource) Delete(c *VCClient) error {
+	return c.deleteSecret(r.path)
+}
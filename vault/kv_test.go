@@ -0,0 +1,427 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKVPaths(t *testing.T) {
+	mounts := map[string]*api.MountOutput{
+		"secret/":      {Type: "kv", Options: map[string]string{"version": "2"}},
+		"secret/team/": {Type: "kv"},
+		"example/":     {Type: "generic"},
+	}
+	tests := []struct {
+		name    string
+		path    string
+		mount   string
+		version string
+	}{
+		{name: "beneath a mount", path: "secret/app/config", mount: "secret", version: "2"},
+		{name: "longest matching mount", path: "secret/team/config", mount: "secret/team"},
+		{name: "mount's own path", path: "secret", mount: "secret", version: "2"},
+		{name: "partial path segment", path: "secrets/app"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mount, mo := findMount(mounts, tt.path)
+			assert.Equal(t, tt.mount, mount, "Secrets should be found beneath the longest matching mount")
+			if tt.mount == "" {
+				assert.Nil(t, mo, "Mounts should only match whole path segments")
+				return
+			}
+			assert.Equal(t, tt.version, mo.Options["version"], "The mount's options should be returned")
+		})
+	}
+}
+
+func TestKVPath(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		prefix string
+		want   string
+	}{
+		{"data", "secret/app/config", "data", "secret/data/app/config"},
+		{"metadata", "secret/app/config", "metadata", "secret/metadata/app/config"},
+		{"mount", "secret", "metadata", "secret/metadata"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, kvPath("secret", tt.path, tt.prefix), "Version 2 paths should be beneath the %s path", tt.name)
+	}
+}
+
+const kvMountConfig = `
+mount "kv2" {
+  path = "kv2"
+  config {
+    type = "kv"
+    options {
+      version = 2
+    }
+  }
+}
+`
+
+func TestKVMountOptions(t *testing.T) {
+	conf, err := ParseConfig([]byte(kvMountConfig))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	assert.Empty(t, validationMessages(Validate([]ConfigFile{{Name: "mounts.vc", Data: []byte(kvMountConfig)}})),
+		"Mount options should be valid")
+	assert.Equal(t, map[string]string{"version": "2"}, stringMap(conf.Mounts[0].Config.Options),
+		"Options should be sent to Vault as strings")
+}
+
+func TestKVMount_Apply(t *testing.T) {
+	f, c := newFakeVault(t)
+	defer f.Close()
+	c.Parallelism = 1
+	listed := 0
+	f.handle("sys/mounts", func(method string, body map[string]interface{}) map[string]interface{} {
+		listed++
+		return f.mounts("sys/mounts")
+	})
+
+	conf, err := ParseConfig([]byte(kvMountConfig + `
+mount "kv1" {
+  path = "kv1"
+  config {
+    type = "kv"
+  }
+}
+
+secret "app" {
+  path = "kv2/app"
+  data {
+    password = "hunter2"
+  }
+}
+
+secret "team" {
+  path = "kv1/team"
+  data {
+    password = "hunter3"
+  }
+}
+`))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	_, err = c.Apply(context.Background(), conf)
+	assert.NoError(t, err, "Apply should not return an error: %v", err)
+
+	assert.Equal(t, 2, listed, "Mounts should be listed again once after they are created")
+	assert.Equal(t, "hunter2", f.get("kv2/data/app")["data"].(map[string]interface{})["password"],
+		"Secrets beneath a mount created by the apply should be written to its data path")
+	assert.Equal(t, "hunter3", f.get("kv1/team")["password"], "Secrets beneath a version 1 mount should be written to their path")
+}
+
+// fakeKV2 emulates a KV version 2 mount on a fake server, each write to the
+// data path of a secret adds a version
+type fakeKV2 struct {
+	mount    string
+	versions map[string][]map[string]interface{}
+	metadata map[string]map[string]interface{}
+	config   map[string]interface{}
+}
+
+func newFakeKV2(f *fakeVault, mount string) *fakeKV2 {
+	kv := &fakeKV2{
+		mount:    mount,
+		versions: make(map[string][]map[string]interface{}),
+		metadata: make(map[string]map[string]interface{}),
+	}
+	f.handlePrefix(mount, kv.serve)
+
+	return kv
+}
+
+func (kv *fakeKV2) serve(method, path string, body map[string]interface{}) map[string]interface{} {
+	path = strings.TrimPrefix(path, kv.mount+"/")
+	switch {
+	case path == "config":
+		if method == http.MethodGet {
+			return kv.config
+		}
+		kv.config = kvSettingsWrite(kv.config, body)
+	case method == "LIST" && (path == "metadata" || strings.HasPrefix(path, "metadata/")):
+		return kv.list(strings.TrimPrefix(strings.TrimPrefix(path, "metadata"), "/"))
+	case strings.HasPrefix(path, "data/"):
+		p := strings.TrimPrefix(path, "data/")
+		if method == http.MethodGet {
+			versions := kv.versions[p]
+			if len(versions) == 0 {
+				return nil
+			}
+			return map[string]interface{}{
+				"data":     versions[len(versions)-1],
+				"metadata": map[string]interface{}{"version": len(versions)},
+			}
+		}
+		data, _ := body["data"].(map[string]interface{})
+		kv.versions[p] = append(kv.versions[p], data)
+		return map[string]interface{}{"version": len(kv.versions[p])}
+	case strings.HasPrefix(path, "metadata/"):
+		p := strings.TrimPrefix(path, "metadata/")
+		switch method {
+		case http.MethodGet:
+			if kv.versions[p] == nil && kv.metadata[p] == nil {
+				return nil
+			}
+			out := map[string]interface{}{"current_version": len(kv.versions[p])}
+			for k, v := range kv.metadata[p] {
+				out[k] = v
+			}
+			return out
+		case http.MethodDelete:
+			delete(kv.versions, p)
+			delete(kv.metadata, p)
+		default:
+			kv.metadata[p] = kvSettingsWrite(kv.metadata[p], body)
+		}
+	}
+
+	return nil
+}
+
+// kvSettingsWrite updates the settings that are written and leaves the
+// others as they are, delete_version_after is formatted as Vault returns
+// it, e.g. 720h is returned as 720h0m0s
+func kvSettingsWrite(settings, body map[string]interface{}) map[string]interface{} {
+	if settings == nil {
+		settings = make(map[string]interface{})
+	}
+	for k, v := range body {
+		settings[k] = v
+	}
+	if s, ok := body["delete_version_after"].(string); ok {
+		if d, err := time.ParseDuration(s); err == nil {
+			settings["delete_version_after"] = d.String()
+		}
+	}
+
+	return settings
+}
+
+// list returns the keys of the secrets beneath a path, keys with children
+// end in a slash
+func (kv *fakeKV2) list(prefix string) map[string]interface{} {
+	seen := make(map[string]bool)
+	var keys []interface{}
+	for p := range kv.secrets() {
+		if prefix != "" && !strings.HasPrefix(p, prefix+"/") {
+			continue
+		}
+		k := strings.TrimPrefix(strings.TrimPrefix(p, prefix), "/")
+		if i := strings.Index(k, "/"); i >= 0 {
+			k = k[:i+1]
+		}
+		if !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].(string) < keys[j].(string) })
+
+	return map[string]interface{}{"keys": keys}
+}
+
+// secrets returns the path of every secret with versions or metadata
+func (kv *fakeKV2) secrets() map[string]bool {
+	out := make(map[string]bool)
+	for p := range kv.versions {
+		out[p] = true
+	}
+	for p := range kv.metadata {
+		out[p] = true
+	}
+
+	return out
+}
+
+func TestKV2_Apply(t *testing.T) {
+	f, c := newFakeVault(t)
+	defer f.Close()
+	kv := newFakeKV2(f, "kv2")
+	// the mount is upgraded from version 1
+	_, err := c.Logical().Write("sys/mounts/kv2", map[string]interface{}{"type": "kv", "options": map[string]interface{}{"version": "1"}})
+	assert.NoError(t, err, "Mounting should not return an error: %v", err)
+
+	config := kvMountConfig + `
+secret "app" {
+  path = "kv2/team/app"
+  data {
+    password = "%s"
+  }
+}`
+	conf, err := ParseConfig([]byte(fmt.Sprintf(config, "hunter2")))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	applyAndPlan(t, c, conf)
+	assert.Equal(t, map[string]interface{}{"version": "2"}, f.get("sys/mounts/kv2")["options"], "The mount should be upgraded to version 2")
+	assert.Equal(t, []map[string]interface{}{{"password": "hunter2"}}, kv.versions["team/app"], "Secrets should be written beneath the data path")
+
+	data, exists, err := c.ReadSecret("kv2/team/app")
+	assert.NoError(t, err, "Reading secret should not return an error: %v", err)
+	assert.True(t, exists, "Secrets should be read from the data path")
+	assert.Equal(t, map[string]interface{}{"password": "hunter2"}, data, "The data of the latest version should be read")
+	paths, err := c.WalkVault("kv2")
+	assert.NoError(t, err, "WalkVault should not return an error: %v", err)
+	assert.Equal(t, []string{"kv2/team/app"}, paths, "Secrets should be listed by their logical path")
+
+	conf, err = ParseConfig([]byte(fmt.Sprintf(config, "hunter3")))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	applyAndPlan(t, c, conf)
+	assert.Len(t, kv.versions["team/app"], 2, "Changed secrets should be written as a new version")
+
+	changes, err := c.PlanPrune(Config{Mounts: conf.Mounts}, []string{"secret"})
+	assert.NoError(t, err, "Planning prune should return no error: %v", err)
+	assert.NoError(t, c.Prune(Config{Mounts: conf.Mounts}, changes), "Pruning secrets should return no error")
+	assert.Empty(t, kv.secrets(), "Every version of pruned secrets should be deleted")
+}
+
+const kvMetadataConfig = `
+mount "kv2" {
+  path = "kv2"
+  config {
+    type = "kv"
+    options {
+      version = "2"
+    }
+  }
+  metadata {
+    max_versions = 10
+  }
+}
+
+secret "app" {
+  path = "kv2/team/app"
+  data {
+    password = "hunter2"
+  }
+  metadata {
+    max_versions = 3
+    cas_required = true
+    delete_version_after = "720h"
+    custom_metadata {
+      owner = "platform"
+    }
+  }
+}
+`
+
+func TestKVMetadata(t *testing.T) {
+	conf, err := ParseConfig([]byte(kvMetadataConfig))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	assert.Empty(t, validationMessages(Validate([]ConfigFile{{Name: "kv.vc", Data: []byte(kvMetadataConfig)}})),
+		"Metadata should be valid")
+
+	resources, err := conf.AllResources()
+	assert.NoError(t, err, "Sorting resources should return no error: %v", err)
+	assert.Equal(t, []string{"mount.kv2", "kv_config.kv2", "secret.app", "secret_metadata.app"}, refs(resources),
+		"Metadata should be written after its mount")
+	assert.Equal(t, "kv2/config", resources[1].VaultPath(), "Mount settings should be written to the mount's config")
+}
+
+func TestSecretMetadata_Apply(t *testing.T) {
+	f, c := newFakeVault(t)
+	defer f.Close()
+	kv := newFakeKV2(f, "kv2")
+	conf, err := ParseConfig([]byte(kvMetadataConfig))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	applyAndPlan(t, c, conf)
+	assert.Equal(t, json.Number("10"), kv.config["max_versions"], "Mount settings should be written to the mount's config")
+	assert.Equal(t, map[string]interface{}{
+		"max_versions":         json.Number("3"),
+		"cas_required":         true,
+		"delete_version_after": "720h0m0s",
+		"custom_metadata":      map[string]interface{}{"owner": "platform"},
+	}, kv.metadata["team/app"], "Secret metadata should be written to the metadata path")
+
+	// settings of the mount that are not declared are left as they are,
+	// metadata changed in Vault is written again
+	kv.config["cas_required"] = true
+	kv.metadata["team/app"]["custom_metadata"] = map[string]interface{}{"owner": "security"}
+	p, err := c.Plan(conf)
+	assert.NoError(t, err, "Plan should not return an error: %v", err)
+	var changed []string
+	for _, ch := range p.Changes {
+		for _, field := range ch.Fields {
+			changed = append(changed, fmt.Sprintf("%s.%s %s", ch.Resource, ch.Name, field.Name))
+		}
+	}
+	assert.Equal(t, []string{"secret_metadata.app custom_metadata"}, changed, "Only the changed metadata should be planned")
+	applyAndPlan(t, c, conf)
+	assert.Equal(t, map[string]interface{}{"owner": "platform"}, kv.metadata["team/app"]["custom_metadata"], "Custom metadata should be written again")
+	assert.Equal(t, true, kv.config["cas_required"], "Settings that are not declared should not be written")
+}
+
+func TestKVConfig_Reset(t *testing.T) {
+	f, c := newFakeVault(t)
+	defer f.Close()
+	tests := []struct {
+		name     string
+		settings string
+		want     map[string]interface{}
+	}{
+		{
+			name:     "settings",
+			settings: "max_versions = 5\ncas_required = true\ndelete_version_after = \"1h\"",
+			want:     map[string]interface{}{"max_versions": json.Number("5"), "cas_required": true, "delete_version_after": "1h"},
+		},
+		{
+			name:     "defaults",
+			settings: "max_versions = 0\ncas_required = false\ndelete_version_after = \"\"",
+			want:     map[string]interface{}{"max_versions": json.Number("0"), "cas_required": false, "delete_version_after": "0s"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf, err := ParseConfig([]byte(fmt.Sprintf(`
+mount "kv2" {
+  path = "kv2"
+  config {
+    type = "kv"
+    options {
+      version = "2"
+    }
+  }
+  metadata {
+    %s
+  }
+}
+`, tt.settings)))
+			assert.NoError(t, err, "Parsing config should return no error: %v", err)
+			applyAndPlan(t, c, conf)
+			assert.Equal(t, tt.want, f.get("kv2/config"), "Declared settings should be written even when they are the defaults")
+		})
+	}
+}
+
+func (vsc *vaultServerConfigTestSuite) TestVCClient_KVMetadata() {
+	conf, err := ParseConfig([]byte(kvMetadataConfig))
+	assert.NoError(vsc.T(), err, "Parsing config should not return an error: %v", err)
+	_, err = vsc.vtc.Apply(context.Background(), conf)
+	assert.NoError(vsc.T(), err, "Apply should not return an error: %v", err)
+
+	s, err := vsc.vtc.Logical().Read("kv2/metadata/team/app")
+	assert.NoError(vsc.T(), err, "Reading metadata should not return an error: %v", err)
+	assert.Equal(vsc.T(), json.Number("3"), s.Data["max_versions"], "Secret metadata should be written")
+	s, err = vsc.vtc.Logical().Read("kv2/config")
+	assert.NoError(vsc.T(), err, "Reading mount config should not return an error: %v", err)
+	assert.Equal(vsc.T(), json.Number("10"), s.Data["max_versions"], "Mount settings should be written")
+
+	applyAndPlan(vsc.T(), vsc.vtc, conf, "mount", "kv_config", "secret", "secret_metadata")
+	s, err = vsc.vtc.Logical().Read("kv2/metadata/team/app")
+	assert.NoError(vsc.T(), err, "Reading metadata should not return an error: %v", err)
+	assert.Equal(vsc.T(), json.Number("3"), s.Data["max_versions"], "Secret metadata should be unchanged after applying again")
+}
@@ -2,28 +2,34 @@ package vault
 
 import (
 	"fmt"
-	"log"
 	"net/http"
+	"sort"
 	"strings"
 )
 
-// MountExist checks for the existence of specified mount
-func (c *VCClient) MountExist(name string) bool {
+func init() {
+	Register(ResourceType{Name: "mount", FromConfig: mountResources, List: listMounts, Protected: []string{"sys", "cubbyhole", "identity"}})
+}
+
+// mountKey converts a mount path into the format used as the key
+// when listing mounts
+func mountKey(name string) string {
 	if !strings.HasSuffix(name, "/") {
 		name = fmt.Sprintf("%s/", name)
 	}
-	name = strings.TrimPrefix(name, "/")
 
-	mounts, err := c.Sys().ListMounts()
+	return strings.TrimPrefix(name, "/")
+}
+
+// MountExist checks for the existence of specified mount
+func (c *VCClient) MountExist(name string) (bool, error) {
+	mounts, err := c.currentMounts()
 	if err != nil {
-		log.Fatal(err)
-	}
-	var ok bool
-	if _, ok = mounts[name]; !ok {
-		return false
+		return false, err
 	}
+	_, ok := mounts[mountKey(name)]
 
-	return true
+	return ok, nil
 }
 
 // Mount creates a new mount on Vault server
@@ -58,3 +64,122 @@ func (c *VCClient) TuneMount(path string, config map[string]interface{}) error {
 	}
 	return err
 }
+
+type mountResource struct {
+	m Mount
+}
+
+func mountResources(conf Config) ([]Resource, error) {
+	var out []Resource
+	for _, m := range conf.Mounts {
+		out = append(out, &mountResource{m})
+	}
+
+	return out, nil
+}
+
+// listMounts returns every mount on the server, mounts are named by path
+func listMounts(c *VCClient, conf Config) ([]Resource, error) {
+	mounts, err := c.currentMounts()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(mounts))
+	for k := range mounts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var out []Resource
+	for _, k := range keys {
+		path := strings.TrimSuffix(k, "/")
+		out = append(out, &mountResource{Mount{Name: path, Path: path}})
+	}
+
+	return out, nil
+}
+
+func (r *mountResource) Kind() string      { return "mount" }
+func (r *mountResource) ID() string        { return r.m.Name }
+func (r *mountResource) VaultPath() string { return strings.Trim(r.m.Path, "/") }
+
+func (r *mountResource) DependsOn() []string { return r.m.DependsOn }
+
+func (r *mountResource) Read(c *VCClient) (map[string]interface{}, bool, error) {
+	mounts, err := c.currentMounts()
+	if err != nil {
+		return nil, false, err
+	}
+	mo, ok := mounts[mountKey(r.m.Path)]
+	if !ok {
+		return nil, false, nil
+	}
+
+	options := make(map[string]interface{}, len(mo.Options))
+	for k, v := range mo.Options {
+		options[k] = v
+	}
+
+	return map[string]interface{}{
+		"type":              mo.Type,
+		"description":       mo.Description,
+		"options":           options,
+		"default_lease_ttl": mo.Config.DefaultLeaseTTL,
+		"max_lease_ttl":     mo.Config.MaxLeaseTTL,
+	}, true, nil
+}
+
+func (r *mountResource) Diff(state map[string]interface{}, exists bool) Change {
+	desired := ConvertMapStringInterface(r.m.Config)
+	for k, v := range ConvertMapStringInterface(r.m.Config.MountConfig) {
+		desired[k] = v
+	}
+	// generic mounts are reported as kv by newer versions of Vault
+	if exists && r.m.Config.PathType == "generic" && state["type"] == "kv" {
+		current := make(map[string]interface{})
+		for k, v := range state {
+			current[k] = v
+		}
+		current["type"] = "generic"
+		state = current
+	}
+
+	return diffResource(r.Kind(), r.ID(), r.VaultPath(), desired, state, exists)
+}
+
+func (r *mountResource) Create(c *VCClient) error {
+	config := ConvertMapStringInterface(r.m.Config)
+	if len(r.m.Config.Options) > 0 {
+		config["options"] = stringMap(r.m.Config.Options)
+	}
+	if err := c.Mount(r.m.Path, config); err != nil {
+		return fmt.Errorf("Error creating mount: %v", err)
+	}
+	c.mountChanged()
+
+	return r.Update(c)
+}
+
+// Update tunes the mount, options can be tuned to upgrade a KV mount to
+// version 2
+func (r *mountResource) Update(c *VCClient) error {
+	tune := ConvertMapStringInterface(r.m.Config.MountConfig)
+	if r.m.Config.Description != "" {
+		tune["description"] = r.m.Config.Description
+	}
+	if len(r.m.Config.Options) > 0 {
+		tune["options"] = stringMap(r.m.Config.Options)
+	}
+	if err := c.TuneMount(r.m.Path, tune); err != nil {
+		return fmt.Errorf("Error tuning mount: %v", err)
+	}
+	if len(r.m.Config.Options) > 0 {
+		c.mountChanged()
+	}
+
+	return nil
+}
+
+func (r *mountResource) Delete(c *VCClient) error {
+	return c.Sys().Unmount(r.VaultPath())
+}
@@ -0,0 +1,49 @@
+package vault
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const mountTemplate = `
+mount "app" {
+  path = "app"
+  config {
+    type = "kv"
+    description = "%s"
+    mountconfig {
+      default_lease_ttl = "%s"
+    }
+  }
+}
+`
+
+func TestMount_Reapply(t *testing.T) {
+	f, c := newFakeVault(t)
+	defer f.Close()
+
+	tests := []struct {
+		name        string
+		description string
+		ttl         string
+		seconds     int
+	}{
+		{name: "create", description: "Application secrets", ttl: "1h", seconds: 3600},
+		{name: "unchanged", description: "Application secrets", ttl: "1h", seconds: 3600},
+		{name: "description changed", description: "Team secrets", ttl: "1h", seconds: 3600},
+		{name: "ttl changed", description: "Team secrets", ttl: "2h", seconds: 7200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf, err := ParseConfig([]byte(fmt.Sprintf(mountTemplate, tt.description, tt.ttl)))
+			assert.NoError(t, err, "Parsing config should return no error: %v", err)
+			applyAndPlan(t, c, conf)
+
+			mo := f.get("sys/mounts/app")
+			assert.Equal(t, tt.description, mo["description"], "The mount's description should be tuned")
+			assert.Equal(t, tt.seconds, mo["config"].(map[string]interface{})["default_lease_ttl"], "The mount's config should be tuned")
+		})
+	}
+}
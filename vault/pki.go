@@ -0,0 +1,409 @@
+package vault
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func init() {
+	Register(ResourceType{Name: "pki", New: func() Block { return &PKI{} }, Import: importPKI})
+	Register(ResourceType{Name: "pki_ca", FromConfig: pkiCAResources})
+	Register(ResourceType{Name: "pki_urls", FromConfig: pkiURLResources})
+	Register(ResourceType{Name: "pki_role", FromConfig: pkiRoleResources, List: listPKIRoles})
+}
+
+// PKI configures a PKI secrets engine mounted at Mount, which defaults to
+// the name of the block. The mount itself is declared with a mount block
+type PKI struct {
+	Name    string    `hcl:",key"`
+	Mount   string    `hcl:"mount"`
+	CA      *PKICA    `hcl:"ca"`
+	URLs    *PKIURLs  `hcl:"urls"`
+	Roles   []PKIRole `hcl:"role"`
+	Depends []string  `hcl:"depends_on"`
+}
+
+// PKICA is the CA of a PKI mount, its key is generated by Vault. Type is
+// either root, which is self signed, or intermediate, which writes a CSR to
+// CSRFile to be signed offline and imports the signed certificate from
+// CertificateFile once it exists. Options are passed to Vault when
+// generating, e.g. key_type, key_bits, ttl and organization
+type PKICA struct {
+	Type            string                 `hcl:"type" validate:"required"`
+	CommonName      string                 `hcl:"common_name" validate:"required"`
+	Options         map[string]interface{} `hcl:"options"`
+	CSRFile         string                 `hcl:"csr_file"`
+	CertificateFile string                 `hcl:"certificate_file"`
+}
+
+// PKIURLs are the issuing certificate, CRL and OCSP URLs encoded in
+// certificates issued by the mount
+type PKIURLs struct {
+	IssuingCertificates   []string `hcl:"issuing_certificates" mapstructure:"issuing_certificates"`
+	CRLDistributionPoints []string `hcl:"crl_distribution_points" mapstructure:"crl_distribution_points"`
+	OCSPServers           []string `hcl:"ocsp_servers" mapstructure:"ocsp_servers"`
+}
+
+// PKIRole is a role certificates are issued against, Options are written
+// to the role along with the other fields, e.g. allow_localhost and
+// server_flag
+type PKIRole struct {
+	Name             string                 `hcl:",key"`
+	AllowedDomains   []string               `hcl:"allowed_domains" mapstructure:"allowed_domains"`
+	AllowSubdomains  bool                   `hcl:"allow_subdomains" mapstructure:"allow_subdomains"`
+	AllowBareDomains bool                   `hcl:"allow_bare_domains" mapstructure:"allow_bare_domains"`
+	AllowAnyName     bool                   `hcl:"allow_any_name" mapstructure:"allow_any_name"`
+	KeyType          string                 `hcl:"key_type" mapstructure:"key_type"`
+	KeyBits          int                    `hcl:"key_bits" mapstructure:"key_bits"`
+	TTL              string                 `hcl:"ttl" mapstructure:"ttl"`
+	MaxTTL           string                 `hcl:"max_ttl" mapstructure:"max_ttl"`
+	Options          map[string]interface{} `hcl:"options"`
+}
+
+func (p *PKI) Kind() string { return "pki" }
+func (p *PKI) ID() string   { return p.Name }
+
+func (p PKI) mountPath() string {
+	if m := strings.Trim(p.Mount, "/"); m != "" {
+		return m
+	}
+
+	return p.Name
+}
+
+// data returns the fields of the role to write to Vault
+func (r PKIRole) data() map[string]interface{} {
+	data := flattenOptions(r.Options)
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	for k, v := range ConvertMapStringInterface(r) {
+		data[k] = v
+	}
+
+	return data
+}
+
+// pkiBlocks returns the pki blocks in the configuration
+func pkiBlocks(conf Config) []*PKI {
+	var out []*PKI
+	for _, b := range conf.Resources {
+		if p, ok := b.(*PKI); ok {
+			out = append(out, p)
+		}
+	}
+
+	return out
+}
+
+func pkiCAResources(conf Config) ([]Resource, error) {
+	var out []Resource
+	for _, p := range pkiBlocks(conf) {
+		if p.CA != nil {
+			out = append(out, &pkiCAResource{mount: p.mountPath(), name: p.Name, ca: *p.CA, dependsOn: p.Depends})
+		}
+	}
+
+	return out, nil
+}
+
+func pkiURLResources(conf Config) ([]Resource, error) {
+	var out []Resource
+	for _, p := range pkiBlocks(conf) {
+		if p.URLs == nil {
+			continue
+		}
+		out = append(out, &pathResource{
+			kind:      "pki_urls",
+			name:      p.Name,
+			path:      fmt.Sprintf("%s/config/urls", p.mountPath()),
+			data:      ConvertMapStringInterface(p.URLs),
+			mount:     p.mountPath(),
+			dependsOn: p.Depends,
+		})
+	}
+
+	return out, nil
+}
+
+func pkiRoleResources(conf Config) ([]Resource, error) {
+	var out []Resource
+	for _, p := range pkiBlocks(conf) {
+		for _, r := range p.Roles {
+			out = append(out, &pathResource{
+				kind:      "pki_role",
+				name:      r.Name,
+				path:      fmt.Sprintf("%s/roles/%s", p.mountPath(), r.Name),
+				data:      r.data(),
+				mount:     p.mountPath(),
+				dependsOn: p.Depends,
+			})
+		}
+	}
+
+	return out, nil
+}
+
+// listPKIRoles returns every role in the PKI mounts declared in the configuration
+func listPKIRoles(c *VCClient, conf Config) ([]Resource, error) {
+	var out []Resource
+	for _, p := range pkiBlocks(conf) {
+		exists, err := c.MountExist(p.mountPath())
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			continue
+		}
+		r, err := c.listPathResources("pki_role", fmt.Sprintf("%s/roles", p.mountPath()))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r...)
+	}
+
+	return out, nil
+}
+
+// pkiCAResource is the CA of a PKI mount, it is compared by the type and
+// common name of the CA certificate as the key never leaves Vault
+type pkiCAResource struct {
+	mount     string
+	name      string
+	ca        PKICA
+	dependsOn []string
+}
+
+func (r *pkiCAResource) Kind() string      { return "pki_ca" }
+func (r *pkiCAResource) ID() string        { return r.name }
+func (r *pkiCAResource) VaultPath() string { return fmt.Sprintf("%s/cert/ca", r.mount) }
+
+func (r *pkiCAResource) DependsOn() []string { return r.dependsOn }
+
+// changeOnly as the CA can not be updated, see Update
+func (r *pkiCAResource) changeOnly() {}
+
+// Read returns the type and common name of the CA certificate, the CA
+// does not exist until a certificate has been generated or imported
+func (r *pkiCAResource) Read(c *VCClient) (map[string]interface{}, bool, error) {
+	exists, err := c.MountExist(r.mount)
+	if err != nil || !exists {
+		return nil, false, err
+	}
+	data, exists, err := c.readData(r.VaultPath())
+	if err != nil || !exists {
+		return nil, false, err
+	}
+	cert, _ := data["certificate"].(string)
+	if cert == "" {
+		return nil, false, nil
+	}
+
+	return caState(cert)
+}
+
+// caState returns the type and common name of a PEM encoded CA certificate
+func caState(cert string) (map[string]interface{}, bool, error) {
+	block, _ := pem.Decode([]byte(cert))
+	if block == nil {
+		return nil, false, fmt.Errorf("Error decoding CA certificate: no PEM data found")
+	}
+	c, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, false, fmt.Errorf("Error parsing CA certificate\nError: %v", err)
+	}
+	caType := "intermediate"
+	if bytes.Equal(c.RawSubject, c.RawIssuer) {
+		caType = "root"
+	}
+
+	return map[string]interface{}{
+		"type":        caType,
+		"common_name": c.Subject.CommonName,
+	}, true, nil
+}
+
+func (r *pkiCAResource) Diff(state map[string]interface{}, exists bool) Change {
+	desired := map[string]interface{}{
+		"type":        r.ca.Type,
+		"common_name": r.ca.CommonName,
+	}
+
+	return diffResource(r.Kind(), r.ID(), r.VaultPath(), desired, state, exists)
+}
+
+// Create generates a root CA, or for an intermediate CA imports the signed
+// certificate if it exists and otherwise writes a CSR for it to be signed.
+// The CSR is only generated when the CSR file does not exist so the key
+// it was generated with is not replaced while it is being signed, until the
+// certificate is written an error is returned
+func (r *pkiCAResource) Create(c *VCClient) error {
+	data := flattenOptions(r.ca.Options)
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	data["common_name"] = r.ca.CommonName
+
+	switch r.ca.Type {
+	case "root":
+		path := fmt.Sprintf("%s/root/generate/internal", r.mount)
+		if _, err := c.Logical().Write(path, data); err != nil {
+			return fmt.Errorf("Error generating root CA: %s\nError: %v", r.name, err)
+		}
+	case "intermediate":
+		if r.ca.CSRFile == "" || r.ca.CertificateFile == "" {
+			return fmt.Errorf("Error configuring intermediate CA: %s\nError: csr_file and certificate_file are required", r.name)
+		}
+		if _, err := os.Stat(r.ca.CertificateFile); err == nil {
+			return r.importCertificate(c)
+		}
+		if _, err := os.Stat(r.ca.CSRFile); os.IsNotExist(err) {
+			return r.generateCSR(c, data)
+		}
+		return fmt.Errorf("Error configuring intermediate CA: %s\nError: the signed certificate is missing, sign %s and write the certificate to %s", r.name, r.ca.CSRFile, r.ca.CertificateFile)
+	default:
+		return fmt.Errorf("Error configuring CA: %s\nError: type should be root or intermediate", r.name)
+	}
+
+	return nil
+}
+
+// Update fails if the type or common name of the CA differs, replacing the
+// CA would invalidate every certificate it has issued so it has to be
+// deleted from Vault by hand to be replaced
+func (r *pkiCAResource) Update(c *VCClient) error {
+	state, exists, err := r.Read(c)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return r.Create(c)
+	}
+	if r.Diff(state, exists).Action == ActionNoop {
+		return nil
+	}
+
+	return fmt.Errorf("Error updating CA: %s\nError: the CA of a mount can not be changed, delete it with %s/root to replace it", r.name, r.mount)
+}
+
+func (r *pkiCAResource) Delete(c *VCClient) error {
+	path := fmt.Sprintf("%s/root", r.mount)
+	if _, err := c.Logical().Delete(path); err != nil {
+		return fmt.Errorf("Error deleting Vault path: %s\nError: %v", path, err)
+	}
+
+	return nil
+}
+
+// generateCSR generates the key of an intermediate CA and writes the CSR
+// to the CSR file
+func (r *pkiCAResource) generateCSR(c *VCClient, data map[string]interface{}) error {
+	s, err := c.Logical().Write(fmt.Sprintf("%s/intermediate/generate/internal", r.mount), data)
+	if err != nil || s == nil {
+		return fmt.Errorf("Error generating CSR for intermediate CA: %s\nError: %v", r.name, err)
+	}
+
+	file := r.ca.CSRFile
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return fmt.Errorf("Error creating directory for CSR file: %s\nError: %v", file, err)
+	}
+	csr := strings.TrimRight(fmt.Sprint(s.Data["csr"]), "\n") + "\n"
+	if err := ioutil.WriteFile(file, []byte(csr), 0644); err != nil {
+		return fmt.Errorf("Error writing CSR file: %s\nError: %v", file, err)
+	}
+
+	return nil
+}
+
+// importCertificate sets the signed certificate of an intermediate CA
+func (r *pkiCAResource) importCertificate(c *VCClient) error {
+	cert, err := ioutil.ReadFile(r.ca.CertificateFile)
+	if err != nil {
+		return fmt.Errorf("Error reading certificate file: %s\nError: %v", r.ca.CertificateFile, err)
+	}
+	data := map[string]interface{}{"certificate": string(cert)}
+	if _, err := c.Logical().Write(fmt.Sprintf("%s/intermediate/set-signed", r.mount), data); err != nil {
+		return fmt.Errorf("Error importing certificate for intermediate CA: %s\nError: %v", r.name, err)
+	}
+
+	return nil
+}
+
+// importPKI imports the URLs and roles of PKI mounts, the CA is not
+// imported as its key can not be read from Vault
+func importPKI(c *VCClient, conf Config) ([]Block, error) {
+	var out []Block
+	for _, m := range conf.Mounts {
+		if m.Config.PathType != "pki" {
+			continue
+		}
+		p := &PKI{Name: m.Name}
+		if m.Name != m.Path {
+			p.Mount = m.Path
+		}
+		data, exists, err := c.readData(fmt.Sprintf("%s/config/urls", m.Path))
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			var urls PKIURLs
+			urls.IssuingCertificates, _ = toStringSlice(data["issuing_certificates"])
+			urls.CRLDistributionPoints, _ = toStringSlice(data["crl_distribution_points"])
+			urls.OCSPServers, _ = toStringSlice(data["ocsp_servers"])
+			if len(urls.IssuingCertificates)+len(urls.CRLDistributionPoints)+len(urls.OCSPServers) > 0 {
+				p.URLs = &urls
+			}
+		}
+		keys, err := c.listKeys(fmt.Sprintf("%s/roles", m.Path))
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range keys {
+			data, exists, err := c.readData(fmt.Sprintf("%s/roles/%s", m.Path, k))
+			if err != nil {
+				return nil, err
+			}
+			if exists {
+				p.Roles = append(p.Roles, importPKIRole(k, data))
+			}
+		}
+		if p.URLs != nil || len(p.Roles) > 0 {
+			out = append(out, p)
+		}
+	}
+
+	return out, nil
+}
+
+// importPKIRole converts a role read from Vault into a PKIRole, values that
+// do not have a field are kept in the options
+func importPKIRole(name string, data map[string]interface{}) PKIRole {
+	r := PKIRole{Name: name}
+	r.AllowedDomains, _ = toStringSlice(data["allowed_domains"])
+	r.AllowSubdomains, _ = data["allow_subdomains"].(bool)
+	r.AllowBareDomains, _ = data["allow_bare_domains"].(bool)
+	r.AllowAnyName, _ = data["allow_any_name"].(bool)
+	r.KeyType, _ = data["key_type"].(string)
+	if v, ok := ttlSeconds(data["key_bits"]); ok {
+		r.KeyBits = int(v)
+	}
+	if v, ok := ttlSeconds(data["ttl"]); ok {
+		r.TTL = formatTTL(int(v))
+	}
+	if v, ok := ttlSeconds(data["max_ttl"]); ok {
+		r.MaxTTL = formatTTL(int(v))
+	}
+	options := cleanOptions(data, "allowed_domains", "allow_subdomains", "allow_bare_domains",
+		"allow_any_name", "key_type", "key_bits", "ttl", "max_ttl")
+	if len(options) > 0 {
+		r.Options = options
+	}
+
+	return r
+}
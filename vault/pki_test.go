@@ -0,0 +1,444 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const pkiConfig = `
+mount "pki" {
+  path = "pki"
+  config {
+    type = "pki"
+  }
+}
+
+mount "pki_int" {
+  path = "pki_int"
+  config {
+    type = "pki"
+  }
+}
+
+pki "pki" {
+  ca {
+    type = "root"
+    common_name = "Example Root CA"
+    options {
+      key_type = "ec"
+      key_bits = 256
+      ttl = "87600h"
+    }
+  }
+  urls {
+    issuing_certificates = ["https://vault.example.com/v1/pki/ca"]
+    crl_distribution_points = ["https://vault.example.com/v1/pki/crl"]
+  }
+}
+
+pki "intermediate" {
+  mount = "pki_int"
+  ca {
+    type = "intermediate"
+    common_name = "Example Intermediate CA"
+    csr_file = "pki/int.csr"
+    certificate_file = "pki/int.pem"
+  }
+  role "web" {
+    allowed_domains = ["example.com"]
+    allow_subdomains = true
+    key_type = "rsa"
+    key_bits = 2048
+    ttl = "72h"
+    max_ttl = "720h"
+    options {
+      server_flag = true
+      client_flag = false
+    }
+  }
+}
+`
+
+// pkiResources returns the resources of pkiConfig in the order they are
+// applied
+func pkiResources(t *testing.T) ([]Resource, []string) {
+	conf, err := ParseConfig([]byte(pkiConfig))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	resources, err := conf.AllResources()
+	assert.NoError(t, err, "Sorting resources should return no error: %v", err)
+
+	return resources, refs(resources)
+}
+
+func TestPKI_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   []string
+	}{
+		{name: "valid", config: pkiConfig},
+		{
+			name: "CA without common name",
+			config: `pki "pki" {
+  ca {
+    type = "root"
+  }
+}`,
+			want: []string{"pki.vc:2:3: pki.ca is missing required field: common_name"},
+		},
+		{
+			name: "CA without type",
+			config: `pki "pki" {
+  ca {
+    common_name = "Example Root CA"
+  }
+}`,
+			want: []string{"pki.vc:2:3: pki.ca is missing required field: type"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, validationMessages(Validate([]ConfigFile{{Name: "pki.vc", Data: []byte(tt.config)}})),
+				"CAs should require a type and common name")
+		})
+	}
+}
+
+func TestPKI_Resources(t *testing.T) {
+	resources, order := pkiResources(t)
+	tests := []struct {
+		resource string
+		mount    string
+		path     string
+	}{
+		{"pki_ca.pki", "mount.pki", "pki/cert/ca"},
+		{"pki_urls.pki", "mount.pki", "pki/config/urls"},
+		{"pki_ca.intermediate", "mount.pki_int", "pki_int/cert/ca"},
+		{"pki_role.web", "mount.pki_int", "pki_int/roles/web"},
+	}
+	for _, tt := range tests {
+		i := indexOf(order, tt.resource)
+		if !assert.True(t, indexOf(order, tt.mount) < i, "%s should be written after its mount", tt.resource) {
+			continue
+		}
+		assert.Equal(t, tt.path, resources[i].VaultPath(), "%s should be written beneath its mount", tt.resource)
+	}
+}
+
+func TestPKICA_Diff(t *testing.T) {
+	resources, order := pkiResources(t)
+	root, _, err := caState(string(testCertificate(t, "Example Root CA", time.Now().Add(time.Hour))))
+	assert.NoError(t, err, "Reading a CA certificate should return no error: %v", err)
+	assert.Equal(t, "root", root["type"], "Self signed certificates should be root CAs")
+
+	tests := []struct {
+		name     string
+		resource string
+		state    map[string]interface{}
+		exists   bool
+		action   ChangeAction
+	}{
+		{name: "matching certificate", resource: "pki_ca.pki", state: root, exists: true, action: ActionNoop},
+		{name: "root instead of intermediate", resource: "pki_ca.intermediate", state: root, exists: true, action: ActionUpdate},
+		{
+			name:     "different common name",
+			resource: "pki_ca.pki",
+			state:    map[string]interface{}{"type": "root", "common_name": "Old Root CA"},
+			exists:   true,
+			action:   ActionUpdate,
+		},
+		{name: "no certificate", resource: "pki_ca.pki", action: ActionCreate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ca := resources[indexOf(order, tt.resource)]
+			assert.Equal(t, tt.action, ca.Diff(tt.state, tt.exists).Action, "CAs should be compared by the certificate read from Vault")
+		})
+	}
+}
+
+func TestCAState(t *testing.T) {
+	tests := []struct {
+		name   string
+		cert   string
+		exists bool
+		err    bool
+	}{
+		{name: "certificate", cert: string(testCertificate(t, "Example Root CA", time.Now().Add(time.Hour))), exists: true},
+		{name: "not PEM", cert: "not a certificate", err: true},
+		{name: "not a certificate", cert: "-----BEGIN CERTIFICATE-----\nbm90IGEgY2VydGlmaWNhdGU=\n-----END CERTIFICATE-----\n", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, exists, err := caState(tt.cert)
+			if tt.err {
+				assert.Error(t, err, "Invalid certificates should return an error")
+				return
+			}
+			assert.NoError(t, err, "Reading a CA certificate should return no error: %v", err)
+			assert.Equal(t, tt.exists, exists, "CA should exist once it has a certificate")
+		})
+	}
+}
+
+func TestPKICA_Intermediate(t *testing.T) {
+	f, c := newFakeVault(t)
+	defer f.Close()
+	f.handle("pki_int/intermediate/generate/internal", func(method string, body map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"csr": "-----BEGIN CERTIFICATE REQUEST-----\nY3Ny\n-----END CERTIFICATE REQUEST-----"}
+	})
+	dir, err := ioutil.TempDir("", "pki")
+	assert.NoError(t, err, "Creating temp dir should not return an error: %v", err)
+	defer os.RemoveAll(dir)
+	ca := &pkiCAResource{mount: "pki_int", name: "intermediate", ca: PKICA{
+		Type:            "intermediate",
+		CommonName:      "Example Intermediate CA",
+		CSRFile:         filepath.Join(dir, "int.csr"),
+		CertificateFile: filepath.Join(dir, "int.pem"),
+	}}
+
+	assert.NoError(t, ca.Create(c), "Generating the CSR should not return an error")
+	csr, err := ioutil.ReadFile(ca.ca.CSRFile)
+	assert.NoError(t, err, "The CSR should be written: %v", err)
+	assert.Equal(t, "-----BEGIN CERTIFICATE REQUEST-----\nY3Ny\n-----END CERTIFICATE REQUEST-----\n", string(csr), "The CSR should be written")
+
+	f.resetWrites()
+	err = ca.Create(c)
+	if assert.Error(t, err, "Applying before the CSR is signed should return an error") {
+		assert.Contains(t, err.Error(), "the signed certificate is missing", "The error should say the certificate is missing")
+	}
+	assert.Empty(t, f.writes, "The CSR should not be generated again while it is being signed")
+
+	cert := testCertificate(t, "Example Intermediate CA", time.Now().Add(time.Hour))
+	assert.NoError(t, ioutil.WriteFile(ca.ca.CertificateFile, cert, 0600), "Writing the certificate should not return an error")
+	assert.NoError(t, ca.Create(c), "Importing the certificate should not return an error")
+	assert.Equal(t, []string{"PUT pki_int/intermediate/set-signed"}, f.writes, "The signed certificate should be imported")
+	assert.Equal(t, string(cert), f.get("pki_int/intermediate/set-signed")["certificate"], "The signed certificate should be imported")
+}
+
+func TestPKI_Apply(t *testing.T) {
+	f, c := newFakeVault(t)
+	defer f.Close()
+	var root []byte
+	f.handle("pki/root/generate/internal", func(method string, body map[string]interface{}) map[string]interface{} {
+		root = testCertificate(t, fmt.Sprint(body["common_name"]), time.Now().Add(time.Hour))
+		f.data["pki/root/generate/internal"] = body
+		return map[string]interface{}{"certificate": string(root)}
+	})
+	f.handle("pki/cert/ca", func(method string, body map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"certificate": string(root)}
+	})
+	// Vault returns the TTLs of roles as seconds
+	f.handle("pki/roles/web", func(method string, body map[string]interface{}) map[string]interface{} {
+		if method == http.MethodGet {
+			return f.data["pki/roles/web"]
+		}
+		for _, k := range []string{"ttl", "max_ttl"} {
+			d, _ := time.ParseDuration(fmt.Sprint(body[k]))
+			body[k] = int(d.Seconds())
+		}
+		f.data["pki/roles/web"] = body
+		return nil
+	})
+
+	config := `
+mount "pki" {
+  path = "pki"
+  config {
+    type = "pki"
+  }
+}
+
+pki "pki" {
+  ca {
+    type = "root"
+    common_name = "%s"
+    options {
+      key_type = "ec"
+      key_bits = 256
+    }
+  }
+  urls {
+    issuing_certificates = ["https://vault.example.com/v1/pki/ca"]
+  }
+  role "web" {
+    allowed_domains = ["example.com"]
+    key_type = "rsa"
+    ttl = "72h"
+    max_ttl = "720h"
+    options {
+      server_flag = true
+    }
+  }
+}
+`
+	conf, err := ParseConfig([]byte(fmt.Sprintf(config, "Example Root CA")))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	applyAndPlan(t, c, conf)
+	assert.Equal(t, map[string]interface{}{"common_name": "Example Root CA", "key_type": "ec", "key_bits": json.Number("256")},
+		f.get("pki/root/generate/internal"), "The root CA should be generated with its options")
+	assert.Equal(t, []interface{}{"https://vault.example.com/v1/pki/ca"}, f.get("pki/config/urls")["issuing_certificates"], "URLs should be written")
+	role := f.get("pki/roles/web")
+	assert.Equal(t, 259200, role["ttl"], "Role TTLs should be written")
+	assert.Equal(t, true, role["server_flag"], "Role options should be written")
+
+	// the CA is not generated again, and a CA that differs is not replaced
+	f.resetWrites()
+	applyAndPlan(t, c, conf)
+	assert.NotContains(t, f.writes, "PUT pki/root/generate/internal", "The root CA should not be generated again")
+	conf, err = ParseConfig([]byte(fmt.Sprintf(config, "New Root CA")))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	_, err = c.Apply(context.Background(), conf)
+	if assert.Error(t, err, "Changing the CA should return an error") {
+		assert.Contains(t, err.Error(), "the CA of a mount can not be changed", "The error should say the CA can not be changed")
+	}
+	assert.Equal(t, "Example Root CA", f.get("pki/root/generate/internal")["common_name"], "The CA should not be replaced")
+}
+
+func TestImportPKIRole(t *testing.T) {
+	imported := importPKIRole("web", map[string]interface{}{
+		"allowed_domains": []interface{}{"example.com"},
+		"key_bits":        json.Number("2048"),
+		"ttl":             json.Number("259200"),
+		"server_flag":     true,
+		"allow_localhost": true,
+	})
+	assert.Equal(t, "72h", imported.TTL, "Imported TTLs should be durations")
+	assert.Equal(t, 2048, imported.KeyBits, "Imported key bits should be set")
+	assert.Equal(t, []string{"example.com"}, imported.AllowedDomains, "Imported domains should be set")
+	assert.Equal(t, map[string]interface{}{"server_flag": true, "allow_localhost": true}, imported.Options,
+		"Values without a field should be imported as options")
+}
+
+func TestImportPKI(t *testing.T) {
+	f, c := newFakeVault(t)
+	defer f.Close()
+	for path, data := range map[string]map[string]interface{}{
+		"sys/mounts/pki":       {"type": "pki"},
+		"sys/mounts/secret":    {"type": "kv"},
+		"pki/config/urls":      {"issuing_certificates": []interface{}{"https://vault.example.com/v1/pki/ca"}},
+		"pki/roles/web":        {"allowed_domains": []interface{}{"example.com"}, "allow_subdomains": true},
+		"secret/config/urls":   {"issuing_certificates": []interface{}{"https://vault.example.com/v1/secret/ca"}},
+		"sys/mounts/pki_empty": {"type": "pki"},
+	} {
+		_, err := c.Logical().Write(path, data)
+		assert.NoError(t, err, "Writing %s should not return an error: %v", path, err)
+	}
+
+	conf, err := c.Import()
+	assert.NoError(t, err, "Import should not return an error: %v", err)
+	var blocks []*PKI
+	for _, b := range conf.Resources {
+		if p, ok := b.(*PKI); ok {
+			blocks = append(blocks, p)
+		}
+	}
+	assert.Equal(t, []*PKI{{
+		Name: "pki",
+		URLs: &PKIURLs{IssuingCertificates: []string{"https://vault.example.com/v1/pki/ca"}},
+		Roles: []PKIRole{{
+			Name:            "web",
+			AllowedDomains:  []string{"example.com"},
+			AllowSubdomains: true,
+		}},
+	}}, blocks, "Only PKI mounts with URLs or roles should be imported as pki blocks")
+
+	// imported blocks are applied as the resources they declare
+	resources, err := conf.AllResources()
+	assert.NoError(t, err, "Sorting resources should return no error: %v", err)
+	var refs []string
+	for _, r := range resources {
+		if strings.HasPrefix(r.Kind(), "pki") {
+			refs = append(refs, resourceRef(r))
+		}
+	}
+	assert.Equal(t, []string{"pki_urls.pki", "pki_role.web"}, refs, "The URLs and roles of imported blocks should be applied")
+}
+
+func (vsc *vaultServerConfigTestSuite) TestVCClient_PKI() {
+	dir, err := ioutil.TempDir("", "pki")
+	assert.NoError(vsc.T(), err, "Creating temp dir should not return an error: %v", err)
+	defer os.RemoveAll(dir)
+	csr, cert := filepath.Join(dir, "int.csr"), filepath.Join(dir, "int.pem")
+
+	conf, err := ParseConfig([]byte(fmt.Sprintf(`
+mount "pki_root" {
+  path = "pki_root"
+  config {
+    type = "pki"
+  }
+}
+
+mount "pki_int" {
+  path = "pki_int"
+  config {
+    type = "pki"
+  }
+}
+
+pki "pki_root" {
+  ca {
+    type = "root"
+    common_name = "Example Root CA"
+  }
+  urls {
+    issuing_certificates = ["https://vault.example.com/v1/pki_root/ca"]
+  }
+}
+
+pki "pki_int" {
+  ca {
+    type = "intermediate"
+    common_name = "Example Intermediate CA"
+    csr_file = "%s"
+    certificate_file = "%s"
+  }
+  role "web" {
+    allowed_domains = ["example.com"]
+    allow_subdomains = true
+    ttl = "72h"
+  }
+}`, csr, cert)))
+	assert.NoError(vsc.T(), err, "Parsing config should not return an error: %v", err)
+	_, err = vsc.vtc.Apply(context.Background(), conf)
+	assert.NoError(vsc.T(), err, "Apply should not return an error: %v", err)
+
+	// sign the CSR with the root CA and import it on the next apply
+	b, err := ioutil.ReadFile(csr)
+	assert.NoError(vsc.T(), err, "CSR should be written for the intermediate CA: %v", err)
+	s, err := vsc.vtc.Logical().Write("pki_root/root/sign-intermediate", map[string]interface{}{
+		"csr":         string(b),
+		"common_name": "Example Intermediate CA",
+	})
+	assert.NoError(vsc.T(), err, "Signing the CSR should not return an error: %v", err)
+	assert.NoError(vsc.T(), ioutil.WriteFile(cert, []byte(fmt.Sprint(s.Data["certificate"])), 0644), "Writing certificate should not return an error")
+	_, err = vsc.vtc.Apply(context.Background(), conf)
+	assert.NoError(vsc.T(), err, "Applying with an existing CA should not return an error: %v", err)
+
+	s, err = vsc.vtc.Logical().Write("pki_int/issue/web", map[string]interface{}{"common_name": "app.example.com"})
+	assert.NoError(vsc.T(), err, "Issuing a certificate should not return an error: %v", err)
+	assert.NotEmpty(vsc.T(), s.Data["certificate"], "Intermediate CA should issue certificates")
+
+	// applying again should not generate or import the CAs again
+	before, err := vsc.vtc.Logical().Read("pki_int/cert/ca")
+	assert.NoError(vsc.T(), err, "Reading CA should not return an error: %v", err)
+	report := applyAndPlan(vsc.T(), vsc.vtc, conf, "mount", "pki_ca", "pki_urls", "pki_role")
+	for _, ch := range report.Changes {
+		if ch.Resource == "pki_ca" {
+			assert.Equal(vsc.T(), ActionNoop, ch.Action, "Existing CAs should not be written again: %s", ch.Name)
+		}
+	}
+	after, err := vsc.vtc.Logical().Read("pki_int/cert/ca")
+	assert.NoError(vsc.T(), err, "Reading CA should not return an error: %v", err)
+	assert.Equal(vsc.T(), before.Data["certificate"], after.Data["certificate"], "Intermediate CA should not change when applied again")
+}
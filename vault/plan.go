@@ -0,0 +1,183 @@
+package vault
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+)
+
+// ChangeAction describes what applying the configuration will do to a resource
+type ChangeAction string
+
+const (
+	ActionCreate ChangeAction = "create"
+	ActionUpdate ChangeAction = "update"
+	ActionNoop   ChangeAction = "no-op"
+	ActionDelete ChangeAction = "delete"
+)
+
+var sensitiveKey = regexp.MustCompile(`(?i)(pass|password|secret|private_key|jwt|token)$`)
+
+// FieldDiff is a single field of a resource that differs between the
+// configuration and the Vault server
+type FieldDiff struct {
+	Name    string
+	Current interface{}
+	Desired interface{}
+}
+
+// Change is the difference between the configuration and the Vault server
+// for a single resource
+type Change struct {
+	Resource  string
+	Name      string
+	Path      string
+	Action    ChangeAction
+	Fields    []FieldDiff
+	Sensitive bool
+}
+
+// Plan contains the changes required to bring the Vault server in line
+// with the configuration
+type Plan struct {
+	Changes []Change
+}
+
+// Plan reads the current state of every resource in the configuration from
+// the Vault server and returns the changes applying the configuration would make
+func (c *VCClient) Plan(conf Config) (*Plan, error) {
+	resources, err := conf.AllResources()
+	if err != nil {
+		return nil, err
+	}
+	s, err := c.withSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkMounts(resources); err != nil {
+		return nil, err
+	}
+
+	p := &Plan{}
+	for _, r := range resources {
+		state, exists, err := r.Read(s)
+		if err != nil {
+			return nil, err
+		}
+		p.Changes = append(p.Changes, r.Diff(state, exists))
+	}
+
+	return p, nil
+}
+
+// Count returns the number of changes in the plan with the specified action
+func (p *Plan) Count(a ChangeAction) (n int) {
+	for _, v := range p.Changes {
+		if v.Action == a {
+			n++
+		}
+	}
+
+	return
+}
+
+// HasChanges returns true if applying the plan would modify the Vault server
+func (p *Plan) HasChanges() bool {
+	return p.Count(ActionCreate)+p.Count(ActionUpdate)+p.Count(ActionDelete) > 0
+}
+
+// Print writes a human readable version of the plan to w, sensitive values
+// are masked
+func (p *Plan) Print(w io.Writer) {
+	symbols := map[ChangeAction]string{
+		ActionCreate: "+",
+		ActionUpdate: "~",
+		ActionNoop:   " ",
+		ActionDelete: "-",
+	}
+	for _, v := range p.Changes {
+		fmt.Fprintf(w, "%s %s.%s (%s)\n", symbols[v.Action], v.Resource, v.Name, v.Path)
+		for _, f := range v.Fields {
+			desired := formatValue(f.Desired, v.Sensitive || sensitiveKey.MatchString(f.Name))
+			if v.Action == ActionCreate {
+				fmt.Fprintf(w, "      %s: %s\n", f.Name, desired)
+				continue
+			}
+			current := formatValue(f.Current, v.Sensitive || sensitiveKey.MatchString(f.Name))
+			fmt.Fprintf(w, "      %s: %s => %s\n", f.Name, current, desired)
+		}
+	}
+	fmt.Fprintf(w, "\nPlan: %d to create, %d to update, %d to delete, %d unchanged.\n",
+		p.Count(ActionCreate), p.Count(ActionUpdate), p.Count(ActionDelete), p.Count(ActionNoop))
+}
+
+func formatValue(v interface{}, sensitive bool) string {
+	if v == nil {
+		return "(none)"
+	}
+	if sensitive {
+		return "(sensitive)"
+	}
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+
+	return fmt.Sprintf("%v", v)
+}
+
+// diffResource builds the change for a resource from the desired and current
+// values of its fields, exists should be false if the resource is not present
+// on the Vault server
+func diffResource(resource, name, path string, desired, current map[string]interface{}, exists bool) Change {
+	return diffValues(resource, name, path, desired, current, exists, valuesEqual)
+}
+
+// diffValues builds the change for a resource in the same way as
+// diffResource, comparing the values of fields with equal
+func diffValues(resource, name, path string, desired, current map[string]interface{}, exists bool, equal func(desired, current interface{}) bool) Change {
+	ch := Change{
+		Resource: resource,
+		Name:     name,
+		Path:     path,
+		Action:   ActionNoop,
+	}
+	if !exists {
+		ch.Action = ActionCreate
+	}
+
+	keys := make([]string, 0, len(desired))
+	for k := range desired {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		var cv interface{}
+		if exists {
+			cv = current[k]
+			if equal(desired[k], cv) {
+				continue
+			}
+		}
+		ch.Fields = append(ch.Fields, FieldDiff{Name: k, Current: cv, Desired: desired[k]})
+	}
+	if exists && len(ch.Fields) > 0 {
+		ch.Action = ActionUpdate
+	}
+
+	return ch
+}
+
+// readData reads a path from Vault returning false if there is nothing there
+func (c *VCClient) readData(path string) (map[string]interface{}, bool, error) {
+	s, err := c.Logical().Read(path)
+	if err != nil {
+		return nil, false, fmt.Errorf("Error reading Vault path: %s\nError: %v", path, err)
+	}
+	if s == nil {
+		return nil, false, nil
+	}
+
+	return s.Data, true, nil
+}
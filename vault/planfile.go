@@ -0,0 +1,97 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/elliottsam/vault-config/crypto"
+)
+
+// SavedPlan is a plan together with the rendered and decrypted configuration
+// it was created from, so that it can be applied at a later time
+type SavedPlan struct {
+	Config     Config
+	PruneTypes []string
+	Changes    []Change
+}
+
+// WritePlanFile serialises a saved plan and writes it to disk encrypted
+// with the supplied key
+func WritePlanFile(filename string, key []byte, sp SavedPlan) error {
+	data, err := json.Marshal(sp)
+	if err != nil {
+		return fmt.Errorf("Error serialising plan: %v", err)
+	}
+	e := crypto.EncryptionObject{
+		Key:       key,
+		PlainText: data,
+	}
+	if err := e.Encrypt(); err != nil {
+		return fmt.Errorf("Error encrypting plan: %v", err)
+	}
+	if err := ioutil.WriteFile(filename, []byte(e.WrappedData), 0600); err != nil {
+		return fmt.Errorf("Error writing plan to file: %v", err)
+	}
+
+	return nil
+}
+
+// ReadPlanFile reads and decrypts a plan written by WritePlanFile
+func ReadPlanFile(filename string, key []byte) (*SavedPlan, error) {
+	file, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading plan file: %v", err)
+	}
+	e := crypto.EncryptionObject{
+		Key:         key,
+		WrappedData: string(file),
+	}
+	if err := e.UnwrapCrypto(); err != nil {
+		return nil, fmt.Errorf("Error unwrapping plan file: %v", err)
+	}
+	if err := e.Decrypt(); err != nil {
+		return nil, fmt.Errorf("Error decrypting plan file: %v", err)
+	}
+
+	var sp SavedPlan
+	d := json.NewDecoder(bytes.NewReader(e.PlainText))
+	d.UseNumber()
+	if err := d.Decode(&sp); err != nil {
+		return nil, fmt.Errorf("Error reading plan: %v", err)
+	}
+
+	return &sp, nil
+}
+
+// CheckPlan creates a new plan from the saved configuration and returns an
+// error if it differs from the saved plan, indicating that the Vault server
+// has changed since the plan was made
+func (c *VCClient) CheckPlan(sp *SavedPlan) error {
+	p, err := c.Plan(sp.Config)
+	if err != nil {
+		return err
+	}
+	if len(sp.PruneTypes) > 0 {
+		pruneChanges, err := c.PlanPrune(sp.Config, sp.PruneTypes)
+		if err != nil {
+			return err
+		}
+		p.Changes = append(p.Changes, pruneChanges...)
+	}
+
+	saved, err := json.Marshal(sp.Changes)
+	if err != nil {
+		return err
+	}
+	current, err := json.Marshal(p.Changes)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(saved, current) {
+		return fmt.Errorf("Vault server has changed since the plan was created, create a new plan")
+	}
+
+	return nil
+}
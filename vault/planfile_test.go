@@ -0,0 +1,46 @@
+package vault
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/elliottsam/vault-config/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanFile(t *testing.T) {
+	conf, err := ParseConfig([]byte(hcl_config))
+	if err != nil {
+		t.Fatalf("Error decoding HCL: %v", err)
+	}
+	dir, err := ioutil.TempDir("", "vault-config")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	key := crypto.RandomKey(32)
+	filename := filepath.Join(dir, "plan.vcplan")
+	sp := SavedPlan{
+		Config: conf,
+		Changes: []Change{
+			{Resource: "policy", Name: "example-policy-1", Action: ActionCreate},
+		},
+	}
+	err = WritePlanFile(filename, key, sp)
+	assert.NoError(t, err, "Writing plan file should return no error: %v", err)
+
+	file, _ := ioutil.ReadFile(filename)
+	assert.NotContains(t, string(file), "example-policy-1", "Plan file should be encrypted")
+
+	rp, err := ReadPlanFile(filename, key)
+	assert.NoError(t, err, "Reading plan file should return no error: %v", err)
+	assert.Equal(t, sp.Changes, rp.Changes, "Changes should match saved plan")
+	assert.Equal(t, len(conf.Secrets), len(rp.Config.Secrets), "Secrets should match saved plan")
+	assert.Equal(t, conf.Policies, rp.Config.Policies, "Policies should match saved plan")
+
+	_, err = ReadPlanFile(filename, crypto.RandomKey(32))
+	assert.Error(t, err, "Reading plan file with the wrong key should return an error")
+}
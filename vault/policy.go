@@ -1,23 +1,23 @@
 package vault
 
 import (
-	"log"
+	"fmt"
+	"sort"
+	"strings"
 )
 
-// PolicyExists checks for the existence of a policy
-func (c *VCClient) PolicyExist(name string) bool {
-	pol, err := c.Sys().ListPolicies()
-	if err != nil {
-		log.Fatalf("Error listing policies: %v", err)
-	}
+func init() {
+	Register(ResourceType{Name: "policy", FromConfig: policyResources, List: listPolicies, Protected: []string{"root", "default"}})
+}
 
-	for _, v := range pol {
-		if v == name {
-			return true
-		}
+// PolicyExist checks for the existence of a policy
+func (c *VCClient) PolicyExist(name string) (bool, error) {
+	pol, err := c.currentPolicies()
+	if err != nil {
+		return false, err
 	}
 
-	return false
+	return contains(pol, name), nil
 }
 
 // PolicyAdd adds a new policy
@@ -29,3 +29,71 @@ func (c *VCClient) PolicyAdd(p Policy) error {
 
 	return nil
 }
+
+type policyResource struct {
+	p Policy
+}
+
+func policyResources(conf Config) ([]Resource, error) {
+	var out []Resource
+	for _, p := range conf.Policies {
+		out = append(out, &policyResource{p})
+	}
+
+	return out, nil
+}
+
+func listPolicies(c *VCClient, conf Config) ([]Resource, error) {
+	policies, err := c.currentPolicies()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(policies)
+
+	var out []Resource
+	for _, p := range policies {
+		out = append(out, &policyResource{Policy{Name: p}})
+	}
+
+	return out, nil
+}
+
+func (r *policyResource) Kind() string      { return "policy" }
+func (r *policyResource) ID() string        { return r.p.Name }
+func (r *policyResource) VaultPath() string { return fmt.Sprintf("sys/policy/%s", r.p.Name) }
+
+func (r *policyResource) DependsOn() []string { return r.p.DependsOn }
+
+func (r *policyResource) Read(c *VCClient) (map[string]interface{}, bool, error) {
+	policies, err := c.currentPolicies()
+	if err != nil || !contains(policies, r.p.Name) {
+		return nil, false, err
+	}
+	rules, err := c.Sys().GetPolicy(r.p.Name)
+	if err != nil {
+		return nil, false, fmt.Errorf("Error reading policy: %s\nError: %v", r.p.Name, err)
+	}
+	if rules == "" {
+		return nil, false, nil
+	}
+
+	return map[string]interface{}{"rules": strings.TrimSpace(rules)}, true, nil
+}
+
+func (r *policyResource) Diff(state map[string]interface{}, exists bool) Change {
+	desired := map[string]interface{}{"rules": strings.TrimSpace(r.p.Rules)}
+
+	return diffResource(r.Kind(), r.ID(), r.VaultPath(), desired, state, exists)
+}
+
+func (r *policyResource) Create(c *VCClient) error {
+	return c.PolicyAdd(r.p)
+}
+
+func (r *policyResource) Update(c *VCClient) error {
+	return c.PolicyAdd(r.p)
+}
+
+func (r *policyResource) Delete(c *VCClient) error {
+	return c.Sys().DeletePolicy(r.p.Name)
+}
@@ -0,0 +1,136 @@
+package vault
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PlanPrune returns a delete change for every object on the Vault server of
+// the specified types that is not declared in the configuration
+func (c *VCClient) PlanPrune(conf Config, types []string) ([]Change, error) {
+	resources, err := conf.AllResources()
+	if err != nil {
+		return nil, err
+	}
+	declared := make(map[string]bool)
+	for _, r := range resources {
+		declared[resourceKey(r.Kind(), r.VaultPath())] = true
+	}
+
+	var changes []Change
+	for _, name := range types {
+		t := lookupType(name)
+		if t == nil || t.List == nil {
+			return nil, fmt.Errorf("Unknown resource type for pruning: %s", name)
+		}
+		existing, err := t.List(c, conf)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range existing {
+			if declared[resourceKey(r.Kind(), r.VaultPath())] || contains(t.Protected, r.ID()) {
+				continue
+			}
+			changes = append(changes, deleteChange(r.Kind(), r.ID(), r.VaultPath()))
+		}
+	}
+
+	return changes, nil
+}
+
+// Prune deletes the resources in the supplied delete changes from Vault,
+// the configuration is used to find the resources on the server
+func (c *VCClient) Prune(conf Config, changes []Change) error {
+	listed := make(map[string][]Resource)
+	for _, ch := range changes {
+		if ch.Action != ActionDelete {
+			continue
+		}
+		t := lookupType(ch.Resource)
+		if t == nil || t.List == nil {
+			return fmt.Errorf("Unknown resource type for pruning: %s", ch.Resource)
+		}
+		if _, ok := listed[t.Name]; !ok {
+			existing, err := t.List(c, conf)
+			if err != nil {
+				return err
+			}
+			listed[t.Name] = existing
+		}
+		for _, r := range listed[t.Name] {
+			if r.VaultPath() != ch.Path {
+				continue
+			}
+			if err := r.Delete(c); err != nil {
+				return fmt.Errorf("Error deleting %s: %s\nError: %v", ch.Resource, ch.Path, err)
+			}
+			break
+		}
+	}
+
+	return nil
+}
+
+func deleteChange(resource, name, path string) Change {
+	return Change{
+		Resource: resource,
+		Name:     name,
+		Path:     path,
+		Action:   ActionDelete,
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// listKeys lists the keys beneath a path, returning nil if there are none
+func (c *VCClient) listKeys(path string) ([]string, error) {
+	s, err := c.Logical().List(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing Vault path: %s\nError: %v", path, err)
+	}
+	if s == nil {
+		return nil, nil
+	}
+	keys, ok := s.Data["keys"].([]interface{})
+	if !ok {
+		return nil, nil
+	}
+	var out []string
+	for _, k := range keys {
+		out = append(out, k.(string))
+	}
+	sort.Strings(out)
+
+	return out, nil
+}
+
+// walkKeys recursively lists every secret beneath a path
+func (c *VCClient) walkKeys(path string) ([]string, error) {
+	keys, err := c.listKeys(path)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, k := range keys {
+		if strings.HasSuffix(k, "/") {
+			sub, err := c.walkKeys(fmt.Sprintf("%s/%s", path, strings.TrimSuffix(k, "/")))
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, sub...)
+		} else {
+			out = append(out, fmt.Sprintf("%s/%s", path, k))
+		}
+	}
+
+	return out, nil
+}
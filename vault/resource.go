@@ -0,0 +1,358 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+)
+
+// Resource is a single object on the Vault server that is managed by
+// vault-config, plan, apply, prune and import all work through this interface
+type Resource interface {
+	// Kind returns the type of the resource, e.g. policy
+	Kind() string
+	// ID returns the name of the resource in the configuration
+	ID() string
+	// VaultPath returns the path of the resource on the Vault server
+	VaultPath() string
+	// Read returns the current state of the resource and whether it exists
+	Read(c *VCClient) (map[string]interface{}, bool, error)
+	// Diff compares the current state of the resource with the configuration
+	Diff(state map[string]interface{}, exists bool) Change
+	Create(c *VCClient) error
+	Update(c *VCClient) error
+	Delete(c *VCClient) error
+}
+
+// Block is a block in the configuration, every Resource is a Block. Blocks
+// that are not resources declare several resources, such as a pki block
+// which declares the CA, URLs and roles of a mount
+type Block interface {
+	// Kind returns the name of the block, e.g. pki
+	Kind() string
+	// ID returns the key of the block
+	ID() string
+}
+
+// ResourceType describes a type of resource that can be managed
+type ResourceType struct {
+	// Name is the type of the resource, for types registered with New it is
+	// also the name of the block the resource is declared with
+	Name string
+	// New returns an empty block that a block in the configuration is
+	// decoded into, the block should be a pointer to a struct with hcl tags.
+	// Blocks that are resources are applied, other blocks are only applied
+	// through the FromConfig of the types they declare
+	New func() Block
+	// FromConfig returns the resources declared in the fields of Config or
+	// by blocks of other types, it is only used by the built in types
+	FromConfig func(conf Config) ([]Resource, error)
+	// List returns every resource of this type on the Vault server, types
+	// without List can not be pruned. Resources returned for types
+	// registered with New are written out by import so must be complete
+	List func(c *VCClient, conf Config) ([]Resource, error)
+	// Import returns the blocks of this type on the Vault server for types
+	// registered with New whose blocks are not resources
+	Import func(c *VCClient, conf Config) ([]Block, error)
+	// Protected lists the IDs of resources that are never pruned or imported
+	Protected []string
+}
+
+// order lists the built in resource types in the order they are applied,
+// resources are pruned in reverse order. Types that are not listed are
+// applied after these in the order they are registered
+var order = []string{
+	"mount",
+	"kv_config",
+	"pki_ca",
+	"pki_urls",
+	"pki_role",
+	"database_connection",
+	"database_role",
+	"audit",
+	"policy",
+	"auth",
+	"auth_config",
+	"auth_user",
+	"auth_group",
+	"auth_role",
+	"token_role",
+	"identity_group",
+	"identity_group_alias",
+	"identity_entity",
+	"secret",
+	"secret_metadata",
+}
+
+// registry holds the registered resource types sorted by order
+var registry []*ResourceType
+
+// Register adds a type of resource, it is intended to be called from init
+// so a new type of resource can be added in a single file
+func Register(t ResourceType) {
+	if t.Name == "" || (t.New == nil && t.FromConfig == nil) {
+		panic("vault: resource types must have a Name and New or FromConfig")
+	}
+	if lookupType(t.Name) != nil {
+		panic(fmt.Sprintf("vault: resource type registered twice: %s", t.Name))
+	}
+	i := len(registry)
+	for i > 0 && typeOrder(registry[i-1].Name) > typeOrder(t.Name) {
+		i--
+	}
+	registry = append(registry, nil)
+	copy(registry[i+1:], registry[i:])
+	registry[i] = &t
+}
+
+// typeOrder returns the position of a resource type in order, types that
+// are not listed come last
+func typeOrder(name string) int {
+	for i, n := range order {
+		if n == name {
+			return i
+		}
+	}
+
+	return len(order)
+}
+
+func lookupType(name string) *ResourceType {
+	for _, t := range registry {
+		if t.Name == name {
+			return t
+		}
+	}
+
+	return nil
+}
+
+// PruneTypes returns the resource types that can be removed from Vault
+// when they are no longer in the configuration, in the order they are removed
+func PruneTypes() []string {
+	var types []string
+	for i := len(registry) - 1; i >= 0; i-- {
+		if registry[i].List != nil {
+			types = append(types, registry[i].Name)
+		}
+	}
+
+	return types
+}
+
+// resourceKey uniquely identifies a resource on the Vault server
+func resourceKey(kind, path string) string {
+	return fmt.Sprintf("%s:%s", kind, path)
+}
+
+// AllResources returns every resource in the configuration in the order
+// they are applied, resources are applied in the order their types were
+// registered unless they depend on a resource that comes later
+func (c Config) AllResources() ([]Resource, error) {
+	var out []Resource
+	for _, t := range registry {
+		if t.FromConfig != nil {
+			r, err := t.FromConfig(c)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, r...)
+			continue
+		}
+		for _, b := range c.Resources {
+			if r, ok := b.(Resource); ok && r.Kind() == t.Name {
+				out = append(out, r)
+			}
+		}
+	}
+
+	return sortResources(out)
+}
+
+// ParseConfig decodes HCL into a Config, blocks for registered resource
+// types are decoded into Resources and files referenced by auth backends
+// and resources are read
+func ParseConfig(b []byte) (Config, error) {
+	var conf Config
+	if err := hcl.Unmarshal(b, &conf); err != nil {
+		return conf, err
+	}
+	f, err := hcl.ParseBytes(b)
+	if err != nil {
+		return conf, err
+	}
+	root, ok := f.Node.(*ast.ObjectList)
+	if !ok {
+		return conf, fmt.Errorf("Error parsing config: root should be an object")
+	}
+	if conf.Auth, err = decodeAuth(root); err != nil {
+		return conf, err
+	}
+	for _, a := range conf.authBackends() {
+		if fl, ok := a.(fileLoader); ok {
+			if err := fl.loadFiles(); err != nil {
+				return conf, err
+			}
+		}
+	}
+	for _, t := range registry {
+		if t.New == nil {
+			continue
+		}
+		for _, item := range root.Filter(t.Name).Items {
+			r := t.New()
+			if err := hcl.DecodeObject(r, item); err != nil {
+				return conf, fmt.Errorf("Error decoding %s: %v", t.Name, err)
+			}
+			if fl, ok := r.(fileLoader); ok {
+				if err := fl.loadFiles(); err != nil {
+					return conf, err
+				}
+			}
+			conf.Resources = append(conf.Resources, r)
+		}
+	}
+
+	return conf, nil
+}
+
+// Resources is a list of blocks of registered types, it is encoded as
+// JSON along with the type of each block so it can be decoded again
+type Resources []Block
+
+type encodedResource struct {
+	Kind string
+	Data json.RawMessage
+}
+
+// MarshalJSON encodes the resources with their types
+func (r Resources) MarshalJSON() ([]byte, error) {
+	out := make([]encodedResource, 0, len(r))
+	for _, v := range r {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, encodedResource{Kind: v.Kind(), Data: b})
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes resources using their registered types
+func (r *Resources) UnmarshalJSON(b []byte) error {
+	var in []encodedResource
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+	*r = nil
+	for _, v := range in {
+		t := lookupType(v.Kind)
+		if t == nil || t.New == nil {
+			return fmt.Errorf("Unknown resource type: %s", v.Kind)
+		}
+		res := t.New()
+		if err := json.Unmarshal(v.Data, res); err != nil {
+			return fmt.Errorf("Error decoding %s: %v", v.Kind, err)
+		}
+		*r = append(*r, res)
+	}
+
+	return nil
+}
+
+// pathResource is a resource stored as data at a single path in Vault,
+// such as a token role or secret
+type pathResource struct {
+	kind string
+	name string
+	path string
+	data map[string]interface{}
+	// authMount is the auth backend the path belongs to, if it is not
+	// enabled the resource does not exist
+	authMount string
+	// mount is the secrets engine the path belongs to, if it is not
+	// mounted the resource does not exist
+	mount string
+	// writeOnly ignores fields that Vault does not return, such as
+	// passwords, when diffing
+	writeOnly bool
+	sensitive bool
+	dependsOn []string
+}
+
+func (r *pathResource) Kind() string      { return r.kind }
+func (r *pathResource) ID() string        { return r.name }
+func (r *pathResource) VaultPath() string { return r.path }
+
+func (r *pathResource) DependsOn() []string { return r.dependsOn }
+func (r *pathResource) policies() []string  { return policyNames(r.data) }
+
+func (r *pathResource) Read(c *VCClient) (map[string]interface{}, bool, error) {
+	if r.authMount != "" {
+		exists, err := c.AuthExist(r.authMount)
+		if err != nil || !exists {
+			return nil, false, err
+		}
+	}
+	if r.mount != "" {
+		exists, err := c.MountExist(r.mount)
+		if err != nil || !exists {
+			return nil, false, err
+		}
+	}
+
+	return c.readData(r.path)
+}
+
+func (r *pathResource) Diff(state map[string]interface{}, exists bool) Change {
+	desired := r.data
+	if exists && r.writeOnly {
+		desired = make(map[string]interface{})
+		for k, v := range r.data {
+			if _, ok := state[k]; ok {
+				desired[k] = v
+			}
+		}
+	}
+	ch := diffResource(r.kind, r.name, r.path, desired, state, exists)
+	ch.Sensitive = r.sensitive
+
+	return ch
+}
+
+func (r *pathResource) Create(c *VCClient) error {
+	return r.Update(c)
+}
+
+func (r *pathResource) Update(c *VCClient) error {
+	if _, err := c.Logical().Write(r.path, r.data); err != nil {
+		return fmt.Errorf("Error writing Vault path: %s\nError: %v", r.path, err)
+	}
+
+	return nil
+}
+
+func (r *pathResource) Delete(c *VCClient) error {
+	if _, err := c.Logical().Delete(r.path); err != nil {
+		return fmt.Errorf("Error deleting Vault path: %s\nError: %v", r.path, err)
+	}
+
+	return nil
+}
+
+// listPathResources returns a resource for every key beneath a path
+func (c *VCClient) listPathResources(kind, path string) ([]Resource, error) {
+	keys, err := c.listKeys(path)
+	if err != nil {
+		return nil, err
+	}
+	var out []Resource
+	for _, k := range keys {
+		out = append(out, &pathResource{kind: kind, name: k, path: fmt.Sprintf("%s/%s", path, k)})
+	}
+
+	return out, nil
+}
@@ -0,0 +1,92 @@
+package vault
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testResource struct {
+	Name    string                 `hcl:",key"`
+	Path    string                 `hcl:"path"`
+	Options map[string]interface{} `hcl:"options"`
+}
+
+func init() {
+	Register(ResourceType{Name: "test_resource", New: func() Block { return &testResource{} }})
+}
+
+func (r *testResource) Kind() string      { return "test_resource" }
+func (r *testResource) ID() string        { return r.Name }
+func (r *testResource) VaultPath() string { return r.Path }
+
+func (r *testResource) Read(c *VCClient) (map[string]interface{}, bool, error) {
+	return nil, false, nil
+}
+
+func (r *testResource) Diff(state map[string]interface{}, exists bool) Change {
+	return diffResource(r.Kind(), r.ID(), r.VaultPath(), r.Options, state, exists)
+}
+
+func (r *testResource) Create(c *VCClient) error { return nil }
+func (r *testResource) Update(c *VCClient) error { return nil }
+func (r *testResource) Delete(c *VCClient) error { return nil }
+
+const resourceConfig = `
+mount "example" {
+  path = "example"
+  config {
+    type = "generic"
+  }
+}
+
+test_resource "one" {
+  path = "test/one"
+  options {
+    ttl = "1h"
+  }
+}
+
+test_resource "two" {
+  path = "test/two"
+}
+`
+
+func TestParseConfig(t *testing.T) {
+	conf, err := ParseConfig([]byte(resourceConfig))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	assert.Equal(t, 1, len(conf.Mounts), "Config should contain mount")
+	assert.Equal(t, 2, len(conf.Resources), "Registered resources should be decoded")
+	assert.Equal(t, "one", conf.Resources[0].ID(), "Resource name should be decoded from block key")
+	assert.Equal(t, "test/two", conf.Resources[1].(Resource).VaultPath(), "Resource fields should be decoded")
+
+	resources, err := conf.AllResources()
+	assert.NoError(t, err, "Listing resources should return no error: %v", err)
+	assert.Equal(t, 3, len(resources), "Every resource should be returned")
+	assert.Equal(t, "mount", resources[0].Kind(), "Built in resources should be applied first")
+	assert.Equal(t, "test_resource", resources[2].Kind(), "Registered resources should be applied last")
+}
+
+func TestResources_JSON(t *testing.T) {
+	conf, err := ParseConfig([]byte(resourceConfig))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+
+	b, err := json.Marshal(conf)
+	assert.NoError(t, err, "Encoding config should return no error: %v", err)
+	var decoded Config
+	err = json.Unmarshal(b, &decoded)
+	assert.NoError(t, err, "Decoding config should return no error: %v", err)
+	assert.Equal(t, conf.Resources, decoded.Resources, "Resources should survive encoding")
+
+	err = json.Unmarshal([]byte(`{"Resources":[{"Kind":"unknown","Data":{}}]}`), &decoded)
+	assert.Error(t, err, "Decoding unknown resource types should return an error")
+}
+
+func TestPruneTypes(t *testing.T) {
+	types := PruneTypes()
+	assert.Equal(t, "secret", types[0], "Secrets should be pruned first")
+	assert.Equal(t, "mount", types[len(types)-1], "Mounts should be pruned last")
+	assert.NotContains(t, types, "test_resource", "Types without List can not be pruned")
+	assert.NotContains(t, types, "auth_config", "Types without List can not be pruned")
+}
@@ -1,51 +1,184 @@
 package vault
 
 import (
+	"bytes"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/elliottsam/vault-config/crypto"
 )
 
+func init() {
+	Register(ResourceType{Name: "secret", FromConfig: secretResources, List: listSecrets})
+}
+
 var (
 	wrappedCipherRegex = regexp.MustCompile(`@encrypted_data\((.*)\)`)
 	encodedSecret      = regexp.MustCompile(`@base64\((.*)\)`)
 )
 
 type Secret struct {
-	Name string                 `hcl:",key"`
-	Path string                 `hcl:"path"`
-	Data map[string]interface{} `hcl:"data"`
+	Name      string                 `hcl:",key"`
+	Path      string                 `hcl:"path" validate:"required"`
+	Data      map[string]interface{} `hcl:"data" validate:"required"`
+	Metadata  *SecretMetadata        `hcl:"metadata"`
+	DependsOn []string               `hcl:"depends_on"`
 }
 
-func (c *VCClient) WriteSecret(s Secret) error {
-	var err error
+// decodeSecretData returns a copy of the secret data with any base64
+// encoded values decoded to bytes, which are written to Vault base64
+// encoded so binary values are not mangled
+func decodeSecretData(s Secret) (map[string]interface{}, error) {
+	data := make(map[string]interface{})
 	for k, v := range s.Data {
-		if encodedSecret.MatchString(v.(string)) {
-			s.Data[k], err = base64.StdEncoding.DecodeString(strings.TrimSuffix(strings.TrimPrefix(v.(string), "@base64("), ")"))
+		data[k] = v
+		if str, ok := v.(string); ok && encodedSecret.MatchString(str) {
+			b, err := base64.StdEncoding.DecodeString(strings.TrimSuffix(strings.TrimPrefix(str, "@base64("), ")"))
 			if err != nil {
-				return fmt.Errorf("error decoding base64 encoded secret: %v - %v", s.Name, v.(string))
+				return nil, fmt.Errorf("error decoding base64 encoded secret: %v - %v", s.Name, str)
 			}
+			data[k] = b
 		}
 	}
 
-	_, err = c.Logical().Write(s.Path, s.Data)
+	return data, nil
+}
+
+// WriteSecret writes a secret to Vault, the secret is not written if its
+// data has not changed
+func (c *VCClient) WriteSecret(s Secret) error {
+	data, err := decodeSecretData(s)
+	if err != nil {
+		return err
+	}
+	path := strings.Trim(s.Path, "/")
+	current, exists, err := c.ReadSecret(path)
 	if err != nil {
+		return err
+	}
+	if exists && secretValuesEqual(data, current) {
+		return nil
+	}
+
+	if err := c.writeSecret(path, data); err != nil {
 		return fmt.Errorf("Writing secret: %s\nError: %v", s.Name, err)
 	}
 
 	return nil
 }
 
-func (c *VCClient) secretExist(s Secret) bool {
-	secret, err := c.Logical().Read(s.Path)
-	if err != nil || secret == nil {
+func (c *VCClient) secretExist(s Secret) (bool, error) {
+	_, exists, err := c.ReadSecret(strings.Trim(s.Path, "/"))
+
+	return exists, err
+}
+
+// secretResource is a secret in a generic or kv mount, secrets in KV
+// version 2 mounts are read and written beneath the data path of the mount
+type secretResource struct {
+	pathResource
+}
+
+func (r *secretResource) Read(c *VCClient) (map[string]interface{}, bool, error) {
+	return c.ReadSecret(r.path)
+}
+
+// Diff compares the secret with the data in Vault, keys that are no longer
+// in the configuration are removed when the secret is written so they are
+// reported as changes
+func (r *secretResource) Diff(state map[string]interface{}, exists bool) Change {
+	ch := diffValues(r.kind, r.name, r.path, r.data, state, exists, secretValuesEqual)
+	ch.Sensitive = r.sensitive
+	if !exists {
+		return ch
+	}
+	var removed []string
+	for k := range state {
+		if _, ok := r.data[k]; !ok {
+			removed = append(removed, k)
+		}
+	}
+	sort.Strings(removed)
+	for _, k := range removed {
+		ch.Fields = append(ch.Fields, FieldDiff{Name: k, Current: state[k]})
+		ch.Action = ActionUpdate
+	}
+
+	return ch
+}
+
+func (r *secretResource) changeOnly() {}
+
+// secretValuesEqual compares secret values exactly, unlike config fields
+// secret data is opaque so a duration or comma separated list is not
+// equal to the same value written another way
+func secretValuesEqual(desired, current interface{}) bool {
+	d, err := json.Marshal(desired)
+	if err != nil {
 		return false
 	}
+	c, err := json.Marshal(current)
+	if err != nil {
+		return false
+	}
+
+	return bytes.Equal(d, c)
+}
 
-	return true
+func (r *secretResource) Create(c *VCClient) error {
+	return r.Update(c)
+}
+
+func (r *secretResource) Update(c *VCClient) error {
+	return c.writeSecret(r.path, r.data)
+}
+
+func (r *secretResource) Delete(c *VCClient) error {
+	return c.deleteSecret(r.path)
+}
+
+func secretResources(conf Config) ([]Resource, error) {
+	var out []Resource
+	for _, s := range conf.Secrets {
+		data, err := decodeSecretData(s)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, &secretResource{pathResource{
+			kind:      "secret",
+			name:      s.Name,
+			path:      strings.Trim(s.Path, "/"),
+			data:      data,
+			sensitive: true,
+			dependsOn: s.DependsOn,
+		}})
+	}
+
+	return out, nil
+}
+
+// listSecrets returns every secret in the generic and kv mounts declared
+// in the configuration
+func listSecrets(c *VCClient, conf Config) ([]Resource, error) {
+	var out []Resource
+	for _, m := range conf.Mounts {
+		if m.Config.PathType != "generic" && m.Config.PathType != "kv" {
+			continue
+		}
+		paths, err := c.walkSecrets(strings.Trim(m.Path, "/"))
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range paths {
+			out = append(out, &secretResource{pathResource{kind: "secret", name: p[strings.LastIndex(p, "/")+1:], path: p, sensitive: true}})
+		}
+	}
+
+	return out, nil
 }
 
 func (c *Config) DecryptSecrets(key []byte) error {
@@ -63,6 +196,60 @@ func (c *Config) DecryptSecrets(key []byte) error {
 			}
 		}
 	}
+	for _, a := range c.authBackends() {
+		for path, values := range authValues(a) {
+			for k, v := range values {
+				if str, ok := v.(string); ok && wrappedCipherRegex.MatchString(str) {
+					values[k], err = crypto.DecryptString(str, key)
+					if err != nil {
+						return fmt.Errorf("Error decrypting auth config: %s\nErr: %v", path, err)
+					}
+				}
+			}
+		}
+	}
+	for _, r := range c.Resources {
+		e, ok := r.(encryptable)
+		if !ok {
+			continue
+		}
+		for _, v := range e.encryptedValues() {
+			if wrappedCipherRegex.MatchString(*v) {
+				*v, err = crypto.DecryptString(*v, key)
+				if err != nil {
+					return fmt.Errorf("Error decrypting %s: %s\nErr: %v", r.Kind(), r.ID(), err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// encryptable is implemented by resources with values that may be
+// encrypted, such as passwords
+type encryptable interface {
+	encryptedValues() []*string
+}
+
+// EncryptSensitive encrypts sensitive values in auth backend config,
+// such as passwords, so the config can be safely written to disk
+func (c *Config) EncryptSensitive(key []byte) error {
+	var err error
+	for _, a := range c.authBackends() {
+		for path, values := range authValues(a) {
+			for k, v := range values {
+				str, ok := v.(string)
+				if !ok || !sensitiveKey.MatchString(k) || wrappedCipherRegex.MatchString(str) {
+					continue
+				}
+				values[k], err = crypto.EncryptString(str, key)
+				if err != nil {
+					return fmt.Errorf("Error encrypting auth config: %s\nErr: %v", path, err)
+				}
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -78,14 +265,40 @@ func SecretsEncrypted(c Config) (sf bool) {
 			}
 		}
 	}
-	for _, v := range c.Auth.Ldap.AuthConfig {
-		switch v.(type) {
-		case string:
-			if wrappedCipherRegex.MatchString(v.(string)) {
-				sf = true
+	for _, a := range c.authBackends() {
+		for _, values := range authValues(a) {
+			for _, v := range values {
+				if str, ok := v.(string); ok && wrappedCipherRegex.MatchString(str) {
+					sf = true
+				}
+			}
+		}
+	}
+	for _, r := range c.Resources {
+		if e, ok := r.(encryptable); ok {
+			for _, v := range e.encryptedValues() {
+				if wrappedCipherRegex.MatchString(*v) {
+					sf = true
+				}
 			}
 		}
 	}
 
 	return
 }
+
+// authValues returns the values of an auth backend that may be encrypted,
+// the config, users and groups, keyed by the path they are written to
+func authValues(a AuthType) map[string]map[string]interface{} {
+	values := map[string]map[string]interface{}{
+		fmt.Sprintf("%s/config", Path(a)): a.getAuthConfig(),
+	}
+	for p, v := range a.getUsers() {
+		values[p] = v
+	}
+	for p, v := range a.getGroups() {
+		values[p] = v
+	}
+
+	return values
+}
@@ -0,0 +1,144 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/elliottsam/vault-config/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecretDiff(t *testing.T) {
+	conf, err := ParseConfig([]byte(`
+secret "app" {
+  path = "secret/app"
+  data {
+    password = "hunter2"
+  }
+}`))
+	assert.NoError(t, err, "Parsing config should return no error: %v", err)
+	resources, err := secretResources(conf)
+	assert.NoError(t, err, "Secret resources should return no error: %v", err)
+	r := resources[0]
+	_, ok := r.(changeOnly)
+	assert.True(t, ok, "Secrets should only be written when they change")
+
+	state := map[string]interface{}{"password": "hunter2"}
+	assert.Equal(t, ActionNoop, r.Diff(state, true).Action, "Unchanged secrets should not be written")
+	state["username"] = "admin"
+	ch := r.Diff(state, true)
+	assert.Equal(t, ActionUpdate, ch.Action, "Keys removed from the config should update the secret")
+	assert.Equal(t, "username", ch.Fields[0].Name, "Only the removed key should differ")
+	assert.Nil(t, ch.Fields[0].Desired, "Removed keys should have no desired value")
+
+	// secret values are opaque so are not converted like config fields
+	r = &secretResource{pathResource{kind: "secret", name: "ttl", path: "secret/ttl", data: map[string]interface{}{"ttl": "30m"}}}
+	assert.Equal(t, ActionUpdate, r.Diff(map[string]interface{}{"ttl": "1800"}, true).Action,
+		"A duration should not match the same number of seconds")
+}
+
+func TestSecretValuesEqual(t *testing.T) {
+	tests := []struct {
+		name    string
+		desired interface{}
+		current interface{}
+		equal   bool
+	}{
+		{"same string", "hunter2", "hunter2", true},
+		{"seconds and duration", "30m", "1800", false},
+		{"durations written differently", "30m", "0.5h", false},
+		{"comma separated and list", []interface{}{"a", "b"}, "a,b", false},
+		{"same list", []interface{}{"a", "b"}, []interface{}{"a", "b"}, true},
+		{"list order", []interface{}{"a", "b"}, []interface{}{"b", "a"}, false},
+		{"number read from Vault", 5, json.Number("5"), true},
+		{"number and string", 5, "5", false},
+		{"same data", map[string]interface{}{"a": "1", "b": "2"}, map[string]interface{}{"b": "2", "a": "1"}, true},
+		{"extra key", map[string]interface{}{"a": "1"}, map[string]interface{}{"a": "1", "b": "2"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.equal, secretValuesEqual(tt.desired, tt.current))
+		})
+	}
+}
+
+func TestDecodeSecretData(t *testing.T) {
+	s := Secret{Name: "app", Data: map[string]interface{}{
+		"text":   "plain",
+		"binary": "@base64(/+7dzA==)",
+	}}
+	data, err := decodeSecretData(s)
+	assert.NoError(t, err, "Decoding should return no error: %v", err)
+	assert.Equal(t, "plain", data["text"], "Plain values should not be changed")
+	assert.Equal(t, []byte{0xff, 0xee, 0xdd, 0xcc}, data["binary"], "Base64 values should be decoded to bytes")
+	assert.True(t, secretValuesEqual(data, map[string]interface{}{"text": "plain", "binary": "/+7dzA=="}),
+		"Bytes are written base64 encoded so should match the value read from Vault")
+
+	s.Data["binary"] = "@base64(not base64)"
+	_, err = decodeSecretData(s)
+	assert.Error(t, err, "Invalid base64 should return an error")
+}
+
+func TestSecrets_Apply(t *testing.T) {
+	f, c := newFakeVault(t)
+	defer f.Close()
+	kv := newFakeKV2(f, "kv2")
+	password, err := crypto.EncryptString("hunter2", c.EncryptionKey)
+	assert.NoError(t, err, "Encrypting should not return an error: %v", err)
+	config := kvMountConfig + fmt.Sprintf(`
+mount "secret" {
+  path = "secret"
+  config {
+    type = "kv"
+  }
+}
+
+secret "app" {
+  path = "kv2/app"
+  data {
+    password = "%s"
+    key = "@base64(/+7dzA==)"
+  }
+}
+
+secret "team" {
+  path = "secret/team"
+  data {
+    password = "%s"
+  }
+}
+`, password, password)
+	parse := func() Config {
+		conf, err := ParseConfig([]byte(config))
+		assert.NoError(t, err, "Parsing config should return no error: %v", err)
+		assert.NoError(t, conf.DecryptSecrets(c.EncryptionKey), "Decrypting should return no error")
+		return conf
+	}
+	applyAndPlan(t, c, parse())
+	assert.Equal(t, []map[string]interface{}{{"password": "hunter2", "key": "/+7dzA=="}}, kv.versions["app"],
+		"Secrets should be written decrypted and decoded")
+	assert.Equal(t, map[string]interface{}{"password": "hunter2"}, f.get("secret/team"), "Secrets should be written decrypted")
+
+	// secrets are compared after they are decoded and decrypted, so applying
+	// the same config again writes nothing
+	f.resetWrites()
+	conf := parse()
+	report := applyAndPlan(t, c, conf)
+	for _, ch := range report.Changes {
+		if ch.Resource == "secret" {
+			assert.Equal(t, ActionNoop, ch.Action, "Unchanged secrets should be reported as no-ops: %s", ch.Name)
+		}
+	}
+	assert.NoError(t, c.WriteSecret(conf.Secrets[0]), "Writing secret should not return an error")
+	for _, w := range f.writes {
+		assert.NotContains(t, []string{"PUT kv2/data/app", "PUT secret/team"}, w, "Unchanged secrets should not be written")
+	}
+	assert.Len(t, kv.versions["app"], 1, "Unchanged secrets should not add a version")
+
+	// secrets changed in Vault are written again
+	_, err = c.Logical().Write("secret/team", map[string]interface{}{"password": "changed"})
+	assert.NoError(t, err, "Writing secret should not return an error: %v", err)
+	applyAndPlan(t, c, conf)
+	assert.Equal(t, map[string]interface{}{"password": "hunter2"}, f.get("secret/team"), "Changed secrets should be written again")
+}
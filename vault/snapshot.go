@@ -0,0 +1,107 @@
+package vault
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// snapshot holds the mounts, auth backends and policies on the server when
+// an apply starts, so they are listed once rather than for every resource.
+// Objects created during the apply are not added to the snapshot, except
+// mounts which are listed again by refreshMounts once one has changed
+type snapshot struct {
+	mu       sync.Mutex
+	mounts   map[string]*api.MountOutput
+	auth     map[string]*api.AuthMount
+	policies []string
+	// mountsChanged is set when a mount is created or its options are tuned
+	mountsChanged bool
+}
+
+// withSnapshot returns a copy of the client that reads mounts, auth
+// backends and policies from a snapshot of the server
+func (c *VCClient) withSnapshot() (*VCClient, error) {
+	s := &snapshot{}
+	var err error
+	if s.mounts, err = c.currentMounts(); err != nil {
+		return nil, err
+	}
+	if s.auth, err = c.currentAuth(); err != nil {
+		return nil, err
+	}
+	if s.policies, err = c.currentPolicies(); err != nil {
+		return nil, err
+	}
+
+	return &VCClient{Client: c.Client, Parallelism: c.Parallelism, EncryptionKey: c.EncryptionKey, snap: s}, nil
+}
+
+func (c *VCClient) currentMounts() (map[string]*api.MountOutput, error) {
+	if c.snap != nil {
+		c.snap.mu.Lock()
+		defer c.snap.mu.Unlock()
+		return c.snap.mounts, nil
+	}
+	mounts, err := c.Sys().ListMounts()
+	if err != nil {
+		return nil, fmt.Errorf("Error listing mounts: %v", err)
+	}
+
+	return mounts, nil
+}
+
+// mountChanged marks the mounts in the snapshot as out of date
+func (c *VCClient) mountChanged() {
+	if c.snap != nil {
+		c.snap.mu.Lock()
+		defer c.snap.mu.Unlock()
+		c.snap.mountsChanged = true
+	}
+}
+
+// refreshMounts lists the mounts again if one has changed since they were
+// last listed, so mounts created or upgraded to KV version 2 during an apply
+// are found without listing the mounts for every resource
+func (c *VCClient) refreshMounts() (map[string]*api.MountOutput, error) {
+	if c.snap == nil {
+		return c.currentMounts()
+	}
+	c.snap.mu.Lock()
+	defer c.snap.mu.Unlock()
+	if c.snap.mountsChanged {
+		mounts, err := c.Sys().ListMounts()
+		if err != nil {
+			return nil, fmt.Errorf("Error listing mounts: %v", err)
+		}
+		c.snap.mounts = mounts
+		c.snap.mountsChanged = false
+	}
+
+	return c.snap.mounts, nil
+}
+
+func (c *VCClient) currentAuth() (map[string]*api.AuthMount, error) {
+	if c.snap != nil {
+		return c.snap.auth, nil
+	}
+	auth, err := c.Sys().ListAuth()
+	if err != nil {
+		return nil, fmt.Errorf("Error listing auth backends: %v", err)
+	}
+
+	return auth, nil
+}
+
+func (c *VCClient) currentPolicies() ([]string, error) {
+	if c.snap != nil {
+		return c.snap.policies, nil
+	}
+	policies, err := c.Sys().ListPolicies()
+	if err != nil {
+		return nil, fmt.Errorf("Error listing policies: %v", err)
+	}
+
+	return policies, nil
+}
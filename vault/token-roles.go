@@ -2,14 +2,15 @@ package vault
 
 import "fmt"
 
-func (c *VCClient) tokenRoleExists(tr TokenRole) bool {
+func init() {
+	Register(ResourceType{Name: "token_role", FromConfig: tokenRoleResources, List: listTokenRoles})
+}
+
+func (c *VCClient) tokenRoleExists(tr TokenRole) (bool, error) {
 	path := fmt.Sprintf("auth/token/roles/%s", tr.Name)
-	r, err := c.Logical().Read(path)
-	if err != nil || r == nil {
-		return false
-	}
+	_, exists, err := c.readData(path)
 
-	return true
+	return exists, err
 }
 
 func (c *VCClient) WriteTokenRole(tr TokenRole) error {
@@ -21,3 +22,22 @@ func (c *VCClient) WriteTokenRole(tr TokenRole) error {
 
 	return nil
 }
+
+func tokenRoleResources(conf Config) ([]Resource, error) {
+	var out []Resource
+	for _, tr := range conf.TokenRoles {
+		out = append(out, &pathResource{
+			kind:      "token_role",
+			name:      tr.Name,
+			path:      fmt.Sprintf("auth/token/roles/%s", tr.Name),
+			data:      tr.Options,
+			dependsOn: tr.DependsOn,
+		})
+	}
+
+	return out, nil
+}
+
+func listTokenRoles(c *VCClient, conf Config) ([]Resource, error) {
+	return c.listPathResources("token_role", "auth/token/roles")
+}
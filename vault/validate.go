@@ -0,0 +1,395 @@
+package vault
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/hcl/hcl/parser"
+	"github.com/hashicorp/hcl/hcl/token"
+)
+
+var (
+	ttlKey = regexp.MustCompile(`(?i)(ttl|period)$`)
+
+	// hints for keys that are commonly put in the wrong place
+	keyHints = map[string]string{
+		"mount.mountconfig": "mountconfig belongs inside the config block",
+	}
+
+	policyPathKeys = []string{"capabilities", "policy", "allowed_parameters", "denied_parameters",
+		"required_parameters", "min_wrapping_ttl", "max_wrapping_ttl", "control_group"}
+	policyCapabilities = []string{"create", "read", "update", "delete", "list", "sudo", "deny", "patch"}
+	policyValues       = []string{"read", "write", "sudo", "deny"}
+)
+
+// ConfigFile is a configuration file to be validated
+type ConfigFile struct {
+	Name string
+	Data []byte
+}
+
+// ValidationError is a problem found in a configuration file
+type ValidationError struct {
+	Pos     token.Pos
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.Pos.Filename, e.Pos.Line, e.Pos.Column, e.Message)
+}
+
+type validator struct {
+	filename string
+	errs     []ValidationError
+	// declared records where each named block was first declared
+	declared map[string]token.Pos
+}
+
+// Validate checks configuration files without contacting Vault. Each file
+// is parsed on its own and checked for unknown keys, missing required
+// fields, resources declared more than once, invalid TTLs and invalid
+// policy rules, errors are sorted by file and position
+func Validate(files []ConfigFile) []ValidationError {
+	v := &validator{declared: make(map[string]token.Pos)}
+	for _, f := range files {
+		v.filename = f.Name
+		v.file(f.Data)
+	}
+	sort.SliceStable(v.errs, func(i, j int) bool {
+		a, b := v.errs[i].Pos, v.errs[j].Pos
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+
+	return v.errs
+}
+
+func (v *validator) errorf(pos token.Pos, format string, args ...interface{}) {
+	pos.Filename = v.filename
+	v.errs = append(v.errs, ValidationError{Pos: pos, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) file(data []byte) {
+	f, err := hcl.ParseBytes(data)
+	if err != nil {
+		if pe, ok := err.(*parser.PosError); ok {
+			v.errorf(pe.Pos, "%v", pe.Err)
+		} else {
+			v.errorf(token.Pos{Line: 1, Column: 1}, "%v", err)
+		}
+		return
+	}
+	root, ok := f.Node.(*ast.ObjectList)
+	if !ok {
+		v.errorf(f.Node.Pos(), "configuration should be an object")
+		return
+	}
+
+	for _, item := range root.Items {
+		name := keyName(item.Keys[0])
+		if t := lookupType(name); t != nil && t.New != nil {
+			v.block(name, item, reflect.TypeOf(t.New()).Elem(), true)
+			continue
+		}
+		if name == "auth" {
+			v.block(name, item, reflect.TypeOf(Auth{}), false)
+			continue
+		}
+		field, ok := hclFields(reflect.TypeOf(Config{}))[strings.ToLower(name)]
+		if !ok {
+			v.errorf(item.Pos(), "unknown block type: %s", name)
+			continue
+		}
+		ft := indirectType(field.Type)
+		if ft.Kind() == reflect.Slice {
+			v.block(name, item, indirectType(ft.Elem()), true)
+		} else {
+			v.block(name, item, ft, false)
+		}
+	}
+}
+
+// block checks a block against the struct type it is decoded into, scope
+// is the path of the block used in error messages
+func (v *validator) block(scope string, item *ast.ObjectItem, t reflect.Type, labelled bool) {
+	wantKeys := 1
+	if labelled && hasKeyField(t) {
+		wantKeys = 2
+		if keyOptional(t) && len(item.Keys) == 1 {
+			wantKeys = 1
+		}
+	}
+	if len(item.Keys) != wantKeys {
+		if wantKeys == 2 {
+			v.errorf(item.Pos(), "%s block should have a single name, e.g. %s \"name\" {", scope, scope)
+		} else {
+			v.errorf(item.Pos(), "%s block should not have a name", scope)
+		}
+		return
+	}
+	obj, ok := item.Val.(*ast.ObjectType)
+	if !ok {
+		v.errorf(item.Val.Pos(), "%s should be a block", scope)
+		return
+	}
+	if wantKeys == 2 {
+		label := keyName(item.Keys[1])
+		key := fmt.Sprintf("%s %q", scope, label)
+		if first, ok := v.declared[key]; ok {
+			v.errorf(item.Pos(), "duplicate %s, first declared at %s:%d:%d", key, first.Filename, first.Line, first.Column)
+		} else {
+			pos := item.Pos()
+			pos.Filename = v.filename
+			v.declared[key] = pos
+		}
+	}
+
+	fields := hclFields(t)
+	seen := make(map[string]bool)
+	for _, i := range obj.List.Items {
+		name := keyName(i.Keys[0])
+		field, ok := fields[strings.ToLower(name)]
+		if !ok {
+			msg := fmt.Sprintf("unknown key in %s: %s", scope, name)
+			if hint, ok := keyHints[fmt.Sprintf("%s.%s", scope, strings.ToLower(name))]; ok {
+				msg = fmt.Sprintf("%s, %s", msg, hint)
+			}
+			v.errorf(i.Pos(), "%s", msg)
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		v.field(fmt.Sprintf("%s.%s", scope, name), name, i, field)
+	}
+
+	names := make([]string, 0, len(fields))
+	for n := range fields {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		if contains(validateTags(fields[n]), "required") && !seen[n] {
+			v.errorf(item.Pos(), "%s is missing required field: %s", scope, n)
+		}
+	}
+}
+
+// field checks a key in a block against the struct field it is decoded into
+func (v *validator) field(scope, name string, item *ast.ObjectItem, f reflect.StructField) {
+	t := indirectType(f.Type)
+	switch {
+	case t.Kind() == reflect.Struct:
+		v.block(scope, item, t, false)
+	case t.Kind() == reflect.Slice && indirectType(t.Elem()).Kind() == reflect.Struct:
+		v.block(scope, item, indirectType(t.Elem()), true)
+	case t.Kind() == reflect.Map:
+		if _, ok := item.Val.(*ast.ObjectType); !ok {
+			v.errorf(item.Val.Pos(), "%s should be a block", scope)
+			return
+		}
+		v.options(scope, item.Val)
+	case t.Kind() == reflect.Slice:
+		switch item.Val.(type) {
+		case *ast.ListType, *ast.LiteralType:
+		default:
+			v.errorf(item.Val.Pos(), "%s should be a list", scope)
+		}
+	default:
+		lit, ok := item.Val.(*ast.LiteralType)
+		if !ok {
+			v.errorf(item.Val.Pos(), "%s should be a value", scope)
+			return
+		}
+		v.ttl(scope, name, lit)
+		if contains(validateTags(f), "policy") {
+			v.policy(lit)
+		}
+	}
+}
+
+// options checks the free form values in a map, such as token role options
+func (v *validator) options(scope string, n ast.Node) {
+	switch t := n.(type) {
+	case *ast.ObjectType:
+		for _, i := range t.List.Items {
+			name := keyName(i.Keys[len(i.Keys)-1])
+			if lit, ok := i.Val.(*ast.LiteralType); ok {
+				v.ttl(fmt.Sprintf("%s.%s", scope, name), name, lit)
+				continue
+			}
+			v.options(fmt.Sprintf("%s.%s", scope, name), i.Val)
+		}
+	case *ast.ListType:
+		for _, e := range t.List {
+			v.options(scope, e)
+		}
+	}
+}
+
+// ttl checks the value of keys ending in ttl or period is a duration or a
+// number of seconds
+func (v *validator) ttl(scope, name string, lit *ast.LiteralType) {
+	if !ttlKey.MatchString(name) {
+		return
+	}
+	val := lit.Token.Value()
+	if s, ok := val.(string); ok && s == "" {
+		return
+	}
+	if _, ok := ttlSeconds(val); !ok {
+		v.errorf(lit.Pos(), "%s is not a valid duration: %s", scope, lit.Token.Text)
+	}
+}
+
+// policy checks the syntax of policy rules, positions of errors in the
+// rules are converted to positions in the file
+func (v *validator) policy(lit *ast.LiteralType) {
+	rules, ok := lit.Token.Value().(string)
+	if !ok {
+		v.errorf(lit.Pos(), "policy rules should be a string")
+		return
+	}
+	pos, err := checkPolicyRules(rules)
+	if err == nil {
+		return
+	}
+	p := lit.Pos()
+	if lit.Token.Type == token.HEREDOC {
+		p.Line += pos.Line
+		p.Column = pos.Column
+	} else {
+		p.Line += pos.Line - 1
+		if pos.Line == 1 {
+			p.Column += pos.Column
+		} else {
+			p.Column = pos.Column
+		}
+	}
+	v.errorf(p, "invalid policy rules: %v", err)
+}
+
+// checkPolicyRules returns the position and description of the first
+// problem in policy rules
+func checkPolicyRules(rules string) (token.Pos, error) {
+	f, err := hcl.ParseString(rules)
+	if err != nil {
+		if pe, ok := err.(*parser.PosError); ok {
+			return pe.Pos, pe.Err
+		}
+		return token.Pos{Line: 1, Column: 1}, err
+	}
+	root, ok := f.Node.(*ast.ObjectList)
+	if !ok {
+		return f.Node.Pos(), fmt.Errorf("rules should be an object")
+	}
+	for _, item := range root.Items {
+		name := keyName(item.Keys[0])
+		if name == "name" {
+			continue
+		}
+		if name != "path" {
+			return item.Pos(), fmt.Errorf("unknown key: %s", name)
+		}
+		if len(item.Keys) != 2 {
+			return item.Pos(), fmt.Errorf("path should be followed by the path it applies to")
+		}
+		obj, ok := item.Val.(*ast.ObjectType)
+		if !ok {
+			return item.Val.Pos(), fmt.Errorf("path should be a block")
+		}
+		for _, i := range obj.List.Items {
+			key := keyName(i.Keys[0])
+			if !contains(policyPathKeys, key) {
+				return i.Pos(), fmt.Errorf("unknown key in path: %s", key)
+			}
+			switch key {
+			case "capabilities":
+				list, ok := i.Val.(*ast.ListType)
+				if !ok {
+					return i.Val.Pos(), fmt.Errorf("capabilities should be a list")
+				}
+				for _, e := range list.List {
+					lit, ok := e.(*ast.LiteralType)
+					if !ok || !contains(policyCapabilities, fmt.Sprint(lit.Token.Value())) {
+						return e.Pos(), fmt.Errorf("unknown capability, expected one of: %s", strings.Join(policyCapabilities, ", "))
+					}
+				}
+			case "policy":
+				lit, ok := i.Val.(*ast.LiteralType)
+				if !ok || !contains(policyValues, fmt.Sprint(lit.Token.Value())) {
+					return i.Val.Pos(), fmt.Errorf("unknown policy, expected one of: %s", strings.Join(policyValues, ", "))
+				}
+			}
+		}
+	}
+
+	return token.Pos{}, nil
+}
+
+// hclFields returns the fields of a struct keyed by the lower case name
+// used in HCL, fields without a name such as the block key are left out
+func hclFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("hcl"), ",")[0]
+		if name == "" || name == "-" || f.PkgPath != "" {
+			continue
+		}
+		fields[strings.ToLower(name)] = f
+	}
+
+	return fields
+}
+
+func hasKeyField(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if strings.Contains(t.Field(i).Tag.Get("hcl"), ",key") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// keyOptional returns true if blocks decoded into the struct do not need
+// a name, such as auth backends
+func keyOptional(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if strings.Contains(f.Tag.Get("hcl"), ",key") && contains(validateTags(f), "optional") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func validateTags(f reflect.StructField) []string {
+	return strings.Split(f.Tag.Get("validate"), ",")
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+
+	return t
+}
+
+func keyName(k *ast.ObjectKey) string {
+	if s, ok := k.Token.Value().(string); ok {
+		return s
+	}
+
+	return k.Token.Text
+}
@@ -0,0 +1,90 @@
+package vault
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func validationMessages(errs []ValidationError) []string {
+	var out []string
+	for _, e := range errs {
+		out = append(out, e.Error())
+	}
+
+	return out
+}
+
+func TestValidate(t *testing.T) {
+	errs := validationMessages(Validate([]ConfigFile{{Name: "config.vc", Data: []byte(hcl_config)}}))
+	assert.Contains(t, errs, "config.vc:7:3: unknown key in mount: mountconfig, mountconfig belongs inside the config block",
+		"Keys at the wrong level should be reported")
+	assert.Contains(t, errs, `config.vc:111:1: duplicate secret "test", first declared at config.vc:95:1`,
+		"Duplicate resource names should be reported")
+}
+
+const validateMounts = `
+mount "app1" {
+  config {
+    description = "missing type"
+    mountconfig {
+      default_lease_ttl = "20 hours"
+    }
+  }
+}
+`
+
+const validatePolicies = `
+policy "app1" {
+  rules = <<EOF
+path "example/app1/*" {
+  capabilities = ["read", "lists"]
+}
+EOF
+}
+
+token_role "app1" {
+  options {
+    period = "1h"
+    explicit_max_ttl = true
+  }
+}
+
+mount "app1" {
+  path = "example/app1"
+  config {
+    type = "generic"
+  }
+}
+`
+
+func TestValidate_Files(t *testing.T) {
+	files := []ConfigFile{
+		{Name: "mounts.vc", Data: []byte(validateMounts)},
+		{Name: "policies.vc", Data: []byte(validatePolicies)},
+		{Name: "broken.vc", Data: []byte(`policy "broken" {`)},
+	}
+	errs := validationMessages(Validate(files))
+	expected := []string{
+		"mounts.vc:2:1: mount is missing required field: path",
+		"mounts.vc:3:3: mount.config is missing required field: type",
+		`mounts.vc:6:27: mount.config.mountconfig.default_lease_ttl is not a valid duration: "20 hours"`,
+		"policies.vc:5:27: invalid policy rules: unknown capability, expected one of: create, read, update, delete, list, sudo, deny, patch",
+		"policies.vc:13:24: token_role.options.explicit_max_ttl is not a valid duration: true",
+		`policies.vc:17:1: duplicate mount "app1", first declared at mounts.vc:2:1`,
+	}
+	for _, e := range expected {
+		assert.Contains(t, errs, e, "Validation errors should include file positions")
+	}
+	found := false
+	for _, e := range errs {
+		if strings.HasPrefix(e, "broken.vc:1:") {
+			found = true
+		}
+	}
+	assert.True(t, found, "Syntax errors should be reported with their position: %v", errs)
+
+	errs = validationMessages(Validate([]ConfigFile{{Name: "ok.vc", Data: []byte(resourceConfig)}}))
+	assert.Empty(t, errs, "Valid configuration should not return errors")
+}
@@ -11,6 +11,14 @@ import (
 // VCClient is a wrapper around the Vault api.Client
 type VCClient struct {
 	*api.Client
+	// Parallelism is the number of resources applied at the same time,
+	// resources are applied one at a time if it is less than 1
+	Parallelism int
+	// EncryptionKey is used to encrypt values generated by Vault that are
+	// written to disk, such as AppRole secret IDs
+	EncryptionKey []byte
+
+	snap *snapshot
 }
 
 // Config contains the Vault configuration that will be
@@ -19,36 +27,56 @@ type Config struct {
 	Mounts     []Mount     `hcl:"mount"`
 	Policies   []Policy    `hcl:"policy"`
 	TokenRoles []TokenRole `hcl:"token_role"`
-	Auth       Auth        `hcl:"auth"`
-	Secrets    []Secret    `hcl:"secret"`
+	// Auth is decoded by ParseConfig as the name of each backend is optional
+	Auth    Auth     `hcl:"-"`
+	Secrets []Secret `hcl:"secret"`
+	// Resources holds blocks of registered types, see Register
+	Resources Resources `hcl:"-"`
 }
 
 type Mount struct {
-	Name   string `hcl:",key"`
-	Path   string `hcl:"path"`
-	Config struct {
-		PathType    string `hcl:"type" mapstructure:"type"`
+	Name      string   `hcl:",key"`
+	Path      string   `hcl:"path" validate:"required"`
+	DependsOn []string `hcl:"depends_on"`
+	Config    struct {
+		PathType    string `hcl:"type" mapstructure:"type" validate:"required"`
 		Description string `hcl:"description" mapstructure:"description"`
+		// Options are specific to the type of mount, e.g. version = "2"
+		// for a KV version 2 mount
+		Options     map[string]interface{} `hcl:"options" mapstructure:"options"`
 		MountConfig struct {
 			DefaultLeaseTTL string `hcl:"default_lease_ttl" mapstructure:"default_lease_ttl"`
 			MaxLeaseTTL     string `hcl:"max_lease_ttl" mapstructure:"max_lease_ttl"`
 		} `hcl:"mountconfig"`
-	} `hcl:"config"`
+	} `hcl:"config" validate:"required"`
+	// Metadata is written to the config of a KV version 2 mount
+	Metadata *KVConfig `hcl:"metadata"`
 }
 
 type Policy struct {
-	Name  string `hcl:",key"`
-	Rules string `hcl:"rules"`
+	Name      string   `hcl:",key"`
+	Rules     string   `hcl:"rules" validate:"required,policy"`
+	DependsOn []string `hcl:"depends_on"`
 }
 
+// Auth holds the auth backends, a type can be declared more than once by
+// naming each block and mounting it at a different path, e.g.
+// ldap "corp" { path = "ldap-corp" }
 type Auth struct {
-	Ldap   *Ldap   `hcl:"ldap"`
-	Github *Github `hcl:"github"`
+	Ldap       []*Ldap       `hcl:"ldap"`
+	Github     []*Github     `hcl:"github"`
+	AppRole    []*AppRole    `hcl:"approle"`
+	Userpass   []*Userpass   `hcl:"userpass"`
+	Kubernetes []*Kubernetes `hcl:"kubernetes"`
+	JWT        []*JWT        `hcl:"jwt"`
+	OIDC       []*JWT        `hcl:"oidc"`
+	Cert       []*Cert       `hcl:"cert"`
 }
 
 type TokenRole struct {
-	Name    string                 `hcl:",key"`
-	Options map[string]interface{} `hcl:"options"`
+	Name      string                 `hcl:",key"`
+	Options   map[string]interface{} `hcl:"options"`
+	DependsOn []string               `hcl:"depends_on"`
 }
 
 // NewClient returns a Vault client
@@ -66,30 +94,16 @@ func NewClient(c *api.Config) (*VCClient, error) {
 		return nil, err
 	}
 
-	return &VCClient{client}, nil
+	return &VCClient{Client: client}, nil
 }
 
-// WalkVault will go through a specific path and return the path of all secrets
-func (c *VCClient) WalkVault(path string) (output []string, err error) {
-	s, err := c.Logical().List(path)
-	if err != nil || s == nil {
-		err = fmt.Errorf("Error reading Vault path: %s", path)
-		return
-	}
-	for _, a := range s.Data {
-		for _, v := range a.([]interface{}) {
-			if strings.HasSuffix(v.(string), "/") {
-				sp, err := c.WalkVault(fmt.Sprintf("%s/%s", path, strings.TrimSuffix(v.(string), "/")))
-				if err != nil {
-					err = fmt.Errorf("Error reading Vault path: %s/%s", path, v.(string))
-					return output, err
-				}
-				output = append(output, sp...)
-			} else {
-				output = append(output, fmt.Sprintf("%s/%s", path, v.(string)))
-			}
-		}
+// WalkVault will go through a specific path and return the path of all secrets,
+// secrets in KV version 2 mounts are returned without the data prefix
+func (c *VCClient) WalkVault(path string) ([]string, error) {
+	output, err := c.walkSecrets(strings.Trim(path, "/"))
+	if err != nil || len(output) == 0 {
+		return nil, fmt.Errorf("Error reading Vault path: %s", path)
 	}
 
-	return
+	return output, nil
 }
@@ -42,7 +42,7 @@ func (v *vaultServerConfigTestSuite) SetupSuite() {
 	time.Sleep(td)
 	v.initVaultTestClient()
 	time.Sleep(td)
-	if err := hcl.Decode(&vc, hcl_config); err != nil {
+	if vc, err = ParseConfig([]byte(hcl_config)); err != nil {
 		v.T().Fatalf("Error decoding HCL: %v", err)
 	}
 	if err := hcl.Decode(&pUpdate, policyUpdate); err != nil {
@@ -1,6 +1,7 @@
 package vault
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -9,15 +10,19 @@ import (
 )
 
 func (vsc *vaultServerConfigTestSuite) testAuthBackendEnable(a AuthType) {
-	assert.False(vsc.T(), vsc.vtc.AuthExist(a.GetType()), "Auth should not exist before enable: %s", a.GetType())
-	err := vsc.vtc.AuthEnable(a)
+	exists, err := vsc.vtc.AuthExist(a.GetPath())
+	assert.NoError(vsc.T(), err, "AuthExist should not return an error: %v", err)
+	assert.False(vsc.T(), exists, "Auth should not exist before enable: %s", a.GetPath())
+	err = vsc.vtc.AuthEnable(a)
 	assert.NoError(vsc.T(), err, "AuthEnable should not return an error: %v", err)
-	assert.True(vsc.T(), vsc.vtc.AuthExist(a.GetType()), "AuthExist should return true after enabling auth type: %s", a.GetType())
+	exists, err = vsc.vtc.AuthExist(a.GetPath())
+	assert.NoError(vsc.T(), err, "AuthExist should not return an error: %v", err)
+	assert.True(vsc.T(), exists, "AuthExist should return true after enabling auth type: %s", a.GetPath())
 }
 
 func (vsc *vaultServerConfigTestSuite) testAuthBackendConfiguration(a AuthType) {
 	vsc.vtc.AuthConfigure(a)
-	s, err := vsc.vtc.Logical().Read("auth/" + a.GetType() + "/config")
+	s, err := vsc.vtc.Logical().Read(Path(a) + "/config")
 	assert.NoError(vsc.T(), err, "Should not error reading Auth config path: %v", err)
 	ac := a.getAuthConfig()
 	for k, _ := range ac {
@@ -40,25 +45,29 @@ func (vsc *vaultServerConfigTestSuite) testAuthBackendMountConfiguration(a AuthT
 
 func (vsc *vaultServerConfigTestSuite) TestVCClient_Auth() {
 	// Testing enabling an Auth backends
-	vsc.testAuthBackendEnable(vc.Auth.Ldap)
-	//vsc.testAuthBackendEnable(vc.Auth.Github)
+	vsc.testAuthBackendEnable(vc.Auth.Ldap[0])
+	//vsc.testAuthBackendEnable(vc.Auth.Github[0])
 
 	// Testing configuring Auth backend
-	vsc.testAuthBackendConfiguration(vc.Auth.Ldap)
-	//vsc.testAuthBackendConfiguration(vc.Auth.Github)
+	vsc.testAuthBackendConfiguration(vc.Auth.Ldap[0])
+	//vsc.testAuthBackendConfiguration(vc.Auth.Github[0])
 
 	// Test auth mount tuning has worked as expected
-	vsc.testAuthBackendMountConfiguration(vc.Auth.Ldap)
-	//vsc.testAuthBackendMountConfiguration(vc.Auth.Github)
+	vsc.testAuthBackendMountConfiguration(vc.Auth.Ldap[0])
+	//vsc.testAuthBackendMountConfiguration(vc.Auth.Github[0])
 }
 
 func (vsc *vaultServerConfigTestSuite) TestVCClient_MountsAndSecrets() {
 	// Test creating new mounts from config
 	for _, v := range vc.Mounts {
-		assert.False(vsc.T(), vsc.vtc.MountExist(v.Path), "Mount should not exist at beginning of test: %s", v.Path)
-		err := vsc.vtc.Mount(v.Path, ConvertMapStringInterface(v.Config))
+		exists, err := vsc.vtc.MountExist(v.Path)
+		assert.NoError(vsc.T(), err, "MountExist should not return an error: %v", err)
+		assert.False(vsc.T(), exists, "Mount should not exist at beginning of test: %s", v.Path)
+		err = vsc.vtc.Mount(v.Path, ConvertMapStringInterface(v.Config))
 		assert.NoError(vsc.T(), err, "Creating mount should not cause an error: %v", err)
-		assert.True(vsc.T(), vsc.vtc.MountExist(v.Path), "Mount should exist after creation: %s", v.Path)
+		exists, err = vsc.vtc.MountExist(v.Path)
+		assert.NoError(vsc.T(), err, "MountExist should not return an error: %v", err)
+		assert.True(vsc.T(), exists, "Mount should exist after creation: %s", v.Path)
 	}
 
 	// Test that custom mount configuration has been completed successfully
@@ -79,10 +88,14 @@ func (vsc *vaultServerConfigTestSuite) TestVCClient_MountsAndSecrets() {
 
 	// Test adding secrets to the vault server
 	for _, v := range vc.Secrets {
-		assert.False(vsc.T(), vsc.vtc.secretExist(v), "Secret should not exist before write: %s", v.Name)
-		err := vsc.vtc.WriteSecret(v)
+		exists, err := vsc.vtc.secretExist(v)
+		assert.NoError(vsc.T(), err, "secretExist should not return an error: %v", err)
+		assert.False(vsc.T(), exists, "Secret should not exist before write: %s", v.Name)
+		err = vsc.vtc.WriteSecret(v)
 		assert.NoError(vsc.T(), err, "Writing secret should not return an error: %s", v.Name)
-		assert.True(vsc.T(), vsc.vtc.secretExist(v), "Secret should exist after write: %s", v.Name)
+		exists, err = vsc.vtc.secretExist(v)
+		assert.NoError(vsc.T(), err, "secretExist should not return an error: %v", err)
+		assert.True(vsc.T(), exists, "Secret should exist after write: %s", v.Name)
 		secret, err := vsc.vtc.Logical().Read(v.Path)
 		assert.NoError(vsc.T(), err, "Reading secret path should not return an error: %s", v.Name)
 		assert.Equal(vsc.T(), v.Data, secret.Data, "Secret should match input: %s", v.Name)
@@ -96,10 +109,14 @@ func (vsc *vaultServerConfigTestSuite) TestVCClient_MountsAndSecrets() {
 
 func (vsc *vaultServerConfigTestSuite) TestVCClient_Policy() {
 	for _, v := range vc.Policies {
-		assert.False(vsc.T(), vsc.vtc.PolicyExist(v.Name), "Policy should not exist before add: %s", v.Name)
-		err := vsc.vtc.PolicyAdd(v)
+		exists, err := vsc.vtc.PolicyExist(v.Name)
+		assert.NoError(vsc.T(), err, "PolicyExist should not return an error: %v", err)
+		assert.False(vsc.T(), exists, "Policy should not exist before add: %s", v.Name)
+		err = vsc.vtc.PolicyAdd(v)
 		assert.NoError(vsc.T(), err, "Adding new policy with valid data should return no error: %s", v.Name)
-		assert.True(vsc.T(), vsc.vtc.PolicyExist(v.Name), "Policy should exist after add: %s", v.Name)
+		exists, err = vsc.vtc.PolicyExist(v.Name)
+		assert.NoError(vsc.T(), err, "PolicyExist should not return an error: %v", err)
+		assert.True(vsc.T(), exists, "Policy should exist after add: %s", v.Name)
 		pol, err := vsc.vtc.Sys().GetPolicy(v.Name)
 		assert.NoError(vsc.T(), err, "Getting policy should return no error: %v", err)
 		assert.Equal(vsc.T(), v.Rules, pol, "Policy should match input configuration")
@@ -120,13 +137,123 @@ func (vsc *vaultServerConfigTestSuite) TestVCClient_Policy() {
 
 func (vsc *vaultServerConfigTestSuite) TestVCClient_TokenRole() {
 	for _, v := range vc.TokenRoles {
-		assert.False(vsc.T(), vsc.vtc.tokenRoleExists(v), "Token role should not exist before add: %s", v.Name)
-		err := vsc.vtc.WriteTokenRole(v)
+		exists, err := vsc.vtc.tokenRoleExists(v)
+		assert.NoError(vsc.T(), err, "tokenRoleExists should not return an error: %v", err)
+		assert.False(vsc.T(), exists, "Token role should not exist before add: %s", v.Name)
+		err = vsc.vtc.WriteTokenRole(v)
 		assert.NoError(vsc.T(), err, "Adding new token role should return no error:", v.Name)
-		assert.True(vsc.T(), vsc.vtc.tokenRoleExists(v), "Token role should exist after add: %s", v.Name)
+		exists, err = vsc.vtc.tokenRoleExists(v)
+		assert.NoError(vsc.T(), err, "tokenRoleExists should not return an error: %v", err)
+		assert.True(vsc.T(), exists, "Token role should exist after add: %s", v.Name)
 		path := fmt.Sprintf("auth/token/roles/%s", v.Name)
 		_, err = vsc.vtc.Logical().Read(path)
 		assert.NoError(vsc.T(), err, "Reading token role should return no error: %v", err)
 		//assert.Equal(vsc.T(), v.Options, tr.Data, "Policy should match input configuration")
 	}
 }
+
+func (vsc *vaultServerConfigTestSuite) TestVCClient_Plan() {
+	p, err := vsc.vtc.Plan(vc)
+	assert.NoError(vsc.T(), err, "Creating plan should not return an error: %v", err)
+	for _, v := range p.Changes {
+		switch v.Resource {
+		case "mount", "secret":
+			assert.Equal(vsc.T(), ActionNoop, v.Action, "Existing %s should be unchanged: %s", v.Resource, v.Name)
+		case "policy", "token_role":
+			assert.Equal(vsc.T(), ActionCreate, v.Action, "New %s should be created: %s", v.Resource, v.Name)
+		}
+	}
+	assert.True(vsc.T(), p.HasChanges(), "Plan should contain changes")
+}
+
+func (vsc *vaultServerConfigTestSuite) TestVCClient_Prune() {
+	err := vsc.vtc.PolicyAdd(Policy{Name: "unmanaged-policy", Rules: `path "secret/*" { capabilities = ["read"] }`})
+	assert.NoError(vsc.T(), err, "Adding unmanaged policy should return no error: %v", err)
+
+	changes, err := vsc.vtc.PlanPrune(vc, []string{"policy", "mount", "auth"})
+	assert.NoError(vsc.T(), err, "Planning prune should return no error: %v", err)
+	var pruned []string
+	var policies []Change
+	for _, v := range changes {
+		pruned = append(pruned, fmt.Sprintf("%s/%s", v.Resource, v.Name))
+		if v.Resource == "policy" {
+			policies = append(policies, v)
+		}
+	}
+	assert.Contains(vsc.T(), pruned, "policy/unmanaged-policy", "Unmanaged policy should be pruned")
+	for _, v := range []string{"policy/root", "policy/default", "mount/sys", "mount/cubbyhole", "auth/token"} {
+		assert.NotContains(vsc.T(), pruned, v, "Protected object should never be pruned: %s", v)
+	}
+
+	err = vsc.vtc.Prune(vc, policies)
+	assert.NoError(vsc.T(), err, "Pruning policies should return no error: %v", err)
+	exists, err := vsc.vtc.PolicyExist("unmanaged-policy")
+	assert.NoError(vsc.T(), err, "PolicyExist should not return an error: %v", err)
+	assert.False(vsc.T(), exists, "Unmanaged policy should not exist after prune")
+}
+
+func (vsc *vaultServerConfigTestSuite) TestVCClient_SavedPlan() {
+	conf := Config{
+		Policies: []Policy{{Name: "saved-plan-policy", Rules: `path "secret/*" { capabilities = ["read"] }`}},
+	}
+	p, err := vsc.vtc.Plan(conf)
+	assert.NoError(vsc.T(), err, "Creating plan should not return an error: %v", err)
+	sp := &SavedPlan{Config: conf, Changes: p.Changes}
+
+	assert.NoError(vsc.T(), vsc.vtc.CheckPlan(sp), "Plan should be valid before it is applied")
+	err = vsc.vtc.ApplyPlan(sp.Config, p)
+	assert.NoError(vsc.T(), err, "Applying plan should not return an error: %v", err)
+	exists, err := vsc.vtc.PolicyExist("saved-plan-policy")
+	assert.NoError(vsc.T(), err, "PolicyExist should not return an error: %v", err)
+	assert.True(vsc.T(), exists, "Policy should exist after plan is applied")
+	assert.Error(vsc.T(), vsc.vtc.CheckPlan(sp), "Plan should be rejected once Vault has changed")
+}
+
+func (vsc *vaultServerConfigTestSuite) TestVCClient_Import() {
+	p := Policy{Name: "import-policy", Rules: `path "secret/*" { capabilities = ["read"] }`}
+	err := vsc.vtc.PolicyAdd(p)
+	assert.NoError(vsc.T(), err, "Adding policy should return no error: %v", err)
+
+	conf, err := vsc.vtc.Import()
+	assert.NoError(vsc.T(), err, "Importing should return no error: %v", err)
+	assert.Contains(vsc.T(), conf.Policies, p, "Imported config should contain policy")
+	for _, v := range conf.Policies {
+		assert.NotContains(vsc.T(), lookupType("policy").Protected, v.Name, "Protected policies should not be imported")
+	}
+	for _, v := range conf.Mounts {
+		assert.NotContains(vsc.T(), lookupType("mount").Protected, v.Path, "System mounts should not be imported")
+	}
+}
+
+func (vsc *vaultServerConfigTestSuite) TestVCClient_CheckMounts() {
+	conf := Config{
+		Secrets: []Secret{{Name: "orphan", Path: "missing/orphan", Data: map[string]interface{}{"value": "x"}}},
+	}
+	_, err := vsc.vtc.Plan(conf)
+	assert.Error(vsc.T(), err, "Planning a secret without a mount should return an error")
+
+	conf.Secrets[0].Path = "secret/orphan"
+	_, err = vsc.vtc.Plan(conf)
+	assert.NoError(vsc.T(), err, "Secrets beneath existing mounts should not return an error: %v", err)
+}
+
+func (vsc *vaultServerConfigTestSuite) TestApply() {
+	var conf Config
+	m := Mount{Name: "apply", Path: "example/apply"}
+	m.Config.PathType = "generic"
+	conf.Mounts = append(conf.Mounts, m)
+	conf.Policies = []Policy{{Name: "apply-policy", Rules: `path "example/apply/*" { capabilities = ["read"] }`}}
+
+	r, err := Apply(context.Background(), vsc.vtc.Client, conf)
+	assert.NoError(vsc.T(), err, "Apply should return no error: %v", err)
+	assert.Equal(vsc.T(), 2, len(r.Changes), "Report should contain every resource applied")
+	assert.Equal(vsc.T(), ActionCreate, r.Changes[0].Action, "New mount should be reported as created")
+	exists, err := vsc.vtc.MountExist(m.Path)
+	assert.NoError(vsc.T(), err, "MountExist should not return an error: %v", err)
+	assert.True(vsc.T(), exists, "Mount should exist after apply")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Apply(ctx, vsc.vtc.Client, conf)
+	assert.Error(vsc.T(), err, "Apply should return an error when the context is cancelled")
+}